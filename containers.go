@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -19,12 +21,84 @@ type Container struct {
 	Created int64
 	Status  string
 	Command string
+
+	NetworkSettings ContainerNetworkSettings
+	Mounts          []ContainerMount
+	Labels          map[string]string
+	ImageID         string
+	Condition       ContainerCondition
+	Stats           *ContainerStats `json:",omitempty"`
+	SizeRw          int64           `json:",omitempty"`
+	SizeRootFs      int64           `json:",omitempty"`
+	HostConfig      ContainerHostConfig
+	Config          *ContainerConfig `json:",omitempty"`
+}
+
+type ContainerMount struct {
+	Type        string
+	Name        string
+	Source      string
+	Destination string
+	RW          bool
+}
+
+type ContainerNetworkSettings struct {
+	Networks map[string]ContainerNetwork
+}
+
+type ContainerNetwork struct {
+	NetworkID string
+	IPAddress string
+	Aliases   []string
+}
+
+// networks every container can be attached to, which would only clutter
+// the picture
+var defaultNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+type ContainerNode struct {
+	Id         string
+	Name       string
+	Names      []string
+	Image      string
+	Status     string
+	Command    string
+	Created    int64
+	Ports      []map[string]interface{}    `json:",omitempty"`
+	Links      []ContainerLink             `json:",omitempty"`
+	Shares     []ContainerShare            `json:",omitempty"`
+	Networks   map[string]ContainerNetwork `json:",omitempty"`
+	Mounts     []ContainerMount            `json:",omitempty"`
+	Labels     map[string]string           `json:",omitempty"`
+	Stats      *ContainerStats             `json:",omitempty"`
+	SizeRw     int64                       `json:",omitempty"`
+	SizeRootFs int64                       `json:",omitempty"`
+	Risks      []ContainerRisk             `json:",omitempty"`
 }
 
 type ContainersCommand struct {
-	Dot        bool `short:"d" long:"dot" description:"Show container information as Graphviz dot."`
-	NoTruncate bool `short:"n" long:"no-trunc" description:"Don't truncate the container IDs."`
-	OnlyRunning bool `short:"r" long:"running" description:"Only show running containers, not Exited"`
+	Tree        bool     `short:"t" long:"tree" description:"Show container information as a tree, grouped by image."`
+	JSON        bool     `short:"j" long:"json" description:"Show container information as JSON."`
+	Dot         bool     `short:"d" long:"dot" description:"Show container information as Graphviz dot."`
+	Compose     bool     `long:"compose" description:"With --tree, group containers by Compose project and service instead of by image."`
+	Sizes       bool     `long:"sizes" description:"Chart containers by the size of their writable layer, largest first."`
+	Order       bool     `long:"order" description:"Print the order to start containers in, and to stop them, from their links, shared namespaces, volumes-from and Compose depends_on."`
+	Audit       bool     `long:"audit" description:"List containers that weaken isolation from the host, and exit with an error if any break the policy."`
+	Allow       []string `long:"allow" value-name:"RISK" description:"With --audit, allow a risk: privileged, host-network, host-pid, host-ipc, cap-add, docker-socket, no-seccomp, no-apparmor or root. Can be repeated."`
+	Problems    bool     `long:"problems" description:"List only containers in a bad state: unhealthy, restarting, OOM killed or failed."`
+	Ports       bool     `long:"ports" description:"Show published ports and host port conflicts as a table, or as Graphviz dot with --dot."`
+	Mounts      bool     `long:"mounts" description:"Include volumes, bind mounts and tmpfs mounts in --dot, --svg and --tree output."`
+	NoTruncate  bool     `short:"n" long:"no-trunc" description:"Don't truncate the container IDs."`
+	SVG         bool     `long:"svg" description:"Show container information as an SVG picture, without needing Graphviz."`
+	GraphML     bool     `long:"graphml" description:"Show container information as GraphML."`
+	GEXF        bool     `long:"gexf" description:"Show container information as GEXF."`
+	CSV         bool     `long:"csv" description:"Show one row per container as CSV."`
+	TSV         bool     `long:"tsv" description:"Show one row per container as TSV."`
+	Columns     string   `long:"columns" value-name:"name,image,status" description:"Columns for --csv/--tsv: id, name, image, status, created, ports, links, command, size_rw, size_root_fs, risks (default all)."`
+	Mermaid     bool     `short:"m" long:"mermaid" description:"Show container information as a Mermaid flowchart."`
+	Stats       bool     `long:"stats" description:"Sample CPU, memory and network use of running containers, and colour --dot, --svg and --tree output by how busy they are."`
+	OnlyRunning bool     `short:"r" long:"running" description:"Only show running containers, not Exited"`
+	Filter      []string `short:"f" long:"filter" value-name:"KEY=VALUE" description:"Only show containers matching a filter: label=, name=, status=, ancestor= (which also matches images built on it, when connected to a daemon), before= or since=. Can be repeated."`
 }
 
 var containersCommand ContainersCommand
@@ -32,17 +106,29 @@ var containersCommand ContainersCommand
 func (x *ContainersCommand) Execute(args []string) error {
 
 	var containers *[]Container
+	var images *[]Image
+
+	filters, err := parseFilters(containersCommand.Filter, containerFilterKeys)
+	if err != nil {
+		return err
+	}
+
+	// stats are sampled live, so there is nothing to sample in saved JSON
+	if containersCommand.Stats && globalOptions.Stdin {
+		return fmt.Errorf("--stats needs a connection to the daemon, so can't be used with --stdin")
+	}
 
 	stat, err := os.Stdin.Stat()
 	if err != nil {
-		return fmt.Errorf("error reading stdin stat", err)
+		return fmt.Errorf("error reading stdin stat: %s", err)
 	}
 
+	var client *docker.Client
 	if globalOptions.Stdin && (stat.Mode()&os.ModeCharDevice) == 0 {
 		// read in stdin
 		stdin, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
-			return fmt.Errorf("error reading all input", err)
+			return fmt.Errorf("error reading all input: %s", err)
 		}
 
 		containers, err = parseContainersJSON(stdin)
@@ -51,12 +137,12 @@ func (x *ContainersCommand) Execute(args []string) error {
 		}
 	} else {
 
-		client, err := connect()
+		client, err = connect()
 		if err != nil {
 			return err
 		}
 
-		clientContainers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+		containers, err = listContainers(client)
 		if err != nil {
 			if in_docker := os.Getenv("IN_DOCKER"); len(in_docker) > 0 {
 				return fmt.Errorf("Unable to access Docker socket, please run like this:\n  docker run -it --rm -v /var/run/docker.sock:/var/run/docker.sock nate/dockviz containers <args>\nFor more help, run 'dockviz help'")
@@ -65,33 +151,188 @@ func (x *ContainersCommand) Execute(args []string) error {
 			}
 		}
 
-		var conts []Container
-		for _, container := range clientContainers {
-			conts = append(conts, Container{
-				container.ID,
-				container.Image,
-				container.Names,
-				apiPortToMap(container.Ports),
-				container.Created,
-				container.Status,
-				container.Command,
-			})
+		// ancestor= follows the layers images are built on
+		if len(filters["ancestor"]) > 0 {
+			ver, err := getAPIVersion(client)
+			if err != nil {
+				return err
+			}
+			images, err = listImages(client, ver)
+			if err != nil {
+				return err
+			}
 		}
+	}
+
+	containers, err = filterContainers(containers, filters, images)
+	if err != nil {
+		return err
+	}
 
-		containers = &conts
+	// only the containers being shown are sampled
+	if containersCommand.Stats {
+		if err := collectContainerStats(client, containers); err != nil {
+			return err
+		}
 	}
 
-	if containersCommand.Dot {
-		fmt.Printf(jsonContainersToDot(containers, containersCommand.OnlyRunning))
+	if containersCommand.Sizes {
+		fmt.Print(jsonContainersToSizeChart(containers, containersCommand.OnlyRunning))
+	} else if containersCommand.Order {
+		result, err := jsonContainersToOrder(containers, containersCommand.OnlyRunning)
+		fmt.Print(result)
+		if err != nil {
+			return err
+		}
+	} else if containersCommand.Audit {
+		allowed, err := parseAllowedRisks(containersCommand.Allow)
+		if err != nil {
+			return err
+		}
+		result, violations := jsonContainersToAudit(containers, containersCommand.OnlyRunning, allowed)
+		fmt.Print(result)
+		if violations > 0 {
+			return fmt.Errorf("%d container(s) break the security policy", violations)
+		}
+	} else if containersCommand.Problems {
+		fmt.Print(jsonContainersToProblems(containers, containersCommand.OnlyRunning))
+	} else if containersCommand.Ports {
+		if containersCommand.Dot {
+			fmt.Print(jsonContainerPortsToDot(containers, containersCommand.OnlyRunning))
+		} else {
+			fmt.Print(jsonContainerPortsToTable(containers, containersCommand.OnlyRunning))
+		}
+	} else if containersCommand.Tree && containersCommand.Compose {
+		fmt.Print(jsonContainersToComposeTree(containers, containersCommand.OnlyRunning, containersCommand.NoTruncate))
+	} else if containersCommand.Tree {
+		fmt.Print(jsonContainersToTree(containers, containersCommand.OnlyRunning, containersCommand.NoTruncate, containersCommand.Mounts))
+	} else if containersCommand.JSON {
+		result, err := jsonContainersToJSON(containers, containersCommand.OnlyRunning)
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+	} else if containersCommand.Dot {
+		fmt.Print(jsonContainersToDot(containers, containersCommand.OnlyRunning, containersCommand.Mounts))
+	} else if containersCommand.SVG {
+		fmt.Print(jsonContainersToSVG(containers, containersCommand.OnlyRunning, containersCommand.Mounts))
+	} else if containersCommand.GraphML {
+		result, err := graphToGraphML(jsonContainersToAttributeGraph(containers, containersCommand.OnlyRunning))
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+	} else if containersCommand.GEXF {
+		result, err := graphToGEXF(jsonContainersToAttributeGraph(containers, containersCommand.OnlyRunning))
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+	} else if containersCommand.CSV || containersCommand.TSV {
+		columns, err := parseColumns(containersCommand.Columns, containerColumns)
+		if err != nil {
+			return err
+		}
+		comma := ','
+		if containersCommand.TSV {
+			comma = '\t'
+		}
+		result, err := jsonContainersToTable(containers, containersCommand.OnlyRunning, columns, comma)
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+	} else if containersCommand.Mermaid {
+		fmt.Print(jsonContainersToMermaid(containers, containersCommand.OnlyRunning))
 	} else {
-		return fmt.Errorf("Please specify --dot")
+		return fmt.Errorf("Please specify either --audit, --tree, --json, --dot, --svg, --graphml, --gexf, --csv, --tsv, --mermaid, --order, --ports, --problems, or --sizes")
 	}
 
 	return nil
 }
 
+func listContainers(client *docker.Client) (*[]Container, error) {
+	clientContainers, err := client.ListContainers(docker.ListContainersOptions{All: true, Size: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// the listing leaves out network aliases, health and restart details
+	// and most of the host config, so each container is inspected as well,
+	// a few at a time
+	inspected := make([]*docker.Container, len(clientContainers))
+	err = forEachConcurrently(len(clientContainers), func(i int) error {
+		details, err := client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: clientContainers[i].ID})
+		if err != nil {
+			if stoppedContainer(err) {
+				return nil
+			}
+			return err
+		}
+		inspected[i] = details
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var conts []Container
+	for i, container := range clientContainers {
+		details := inspected[i]
+		if details == nil {
+			// removed since it was listed
+			continue
+		}
+
+		conts = append(conts, Container{
+			container.ID,
+			container.Image,
+			container.Names,
+			apiPortToMap(container.Ports),
+			container.Created,
+			container.Status,
+			container.Command,
+			ContainerNetworkSettings{containerNetworks(container, details)},
+			apiMountsToMounts(container.Mounts),
+			container.Labels,
+			details.Image,
+			containerCondition(details),
+			nil,
+			container.SizeRw,
+			container.SizeRootFs,
+			containerHostConfig(details),
+			containerConfig(details),
+		})
+	}
+
+	return &conts, nil
+}
+
+// containerNetworks returns the networks a container is attached to, with
+// the aliases only inspecting the container gives.
+func containerNetworks(container docker.APIContainers, details *docker.Container) map[string]ContainerNetwork {
+	networks := make(map[string]ContainerNetwork)
+	for name, network := range container.Networks.Networks {
+		networks[name] = ContainerNetwork{network.NetworkID, network.IPAddress, network.Aliases}
+	}
+	if details.NetworkSettings != nil {
+		for name, network := range details.NetworkSettings.Networks {
+			networks[name] = ContainerNetwork{network.NetworkID, network.IPAddress, network.Aliases}
+		}
+	}
+	return networks
+}
+
+func apiMountsToMounts(mounts []docker.APIMount) []ContainerMount {
+	var result []ContainerMount
+	for _, mount := range mounts {
+		result = append(result, ContainerMount{mount.Type, mount.Name, mount.Source, mount.Destination, mount.RW})
+	}
+	return result
+}
+
 func apiPortToMap(ports []docker.APIPort) []map[string]interface{} {
-	result := make([]map[string]interface{}, 2)
+	var result []map[string]interface{}
 	for _, port := range ports {
 		intPort := map[string]interface{}{
 			"IP":          port.IP,
@@ -110,23 +351,493 @@ func parseContainersJSON(rawJSON []byte) (*[]Container, error) {
 	err := json.Unmarshal(rawJSON, &containers)
 
 	if err != nil {
-		return nil, fmt.Errorf("Error reading JSON: ", err)
+		return nil, fmt.Errorf("Error reading JSON: %s", err)
 	}
 
 	return &containers, nil
 }
 
-func jsonContainersToDot(containers *[]Container,OnlyRunning bool) string {
+type ContainerLink struct {
+	Source string
+	Target string
+	Alias  string
+}
+
+func jsonContainersToDot(containers *[]Container, OnlyRunning bool, ShowMounts bool) string {
 
 	var buffer bytes.Buffer
 	buffer.WriteString("digraph docker {\n")
 
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"%s\" [label = \" %s\" ]\n", link.Source, link.Target, link.Alias))
+	}
+
+	for _, share := range collectContainerShares(containers, OnlyRunning) {
+		buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"%s\" [label = \" %s\",style=bold,color=\"%s\",fontcolor=\"%s\" ]\n", share.Source, share.Target, share.label(), shareColors[share.Kind], shareColors[share.Kind]))
+	}
+
+	networks, attachments := collectContainerNetworks(containers, OnlyRunning)
+	for _, network := range networks {
+		buffer.WriteString(fmt.Sprintf(" \"network:%s\" [label=\"%s\",shape=ellipse,fillcolor=\"khaki\",style=\"filled\"];\n", network, network))
+	}
+	for _, attachment := range attachments {
+		buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"network:%s\" [label = \" %s\",dir=none,style=dashed ]\n", attachment.Container, attachment.Network, strings.Join(attachment.labelParts(), "\\n")))
+	}
+
+	if ShowMounts {
+		sources, uses := collectContainerMounts(containers, OnlyRunning)
+		for _, source := range sources {
+			buffer.WriteString(fmt.Sprintf(" \"%s\" [label=\"%s\",shape=%s,fillcolor=\"%s\",style=\"filled\"];\n", source.Id, source.Label, mountShapes[source.Type], mountColors[source.Type]))
+		}
+		for _, use := range uses {
+			style := "solid"
+			if !use.RW {
+				style = "dotted"
+			}
+			buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"%s\" [label = \" %s\",style=%s ]\n", use.Container, use.Source, use.label(), style))
+		}
+	}
+
+	// containers from the same Compose project are drawn in a cluster
+	var projects []string
+	byProject := make(map[string][]string)
+	usedStyles := make(map[string]bool)
+	heat := containerHeat(*containers)
+	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
+
+		containerName := primaryContainerName(container)
+
+		style := containerStyle(container)
+		usedStyles[style.Name] = true
+
+		if container.Stats != nil {
+			style.Fill = heatColor(heat[container.Id])
+		}
+
+		node := fmt.Sprintf("\"%s\" [label=\"%s\",%s];\n", containerName, strings.Join(containerLabelParts(container), "\\n"), style.dotAttributes())
+
+		project := container.Labels[composeProjectLabel]
+		if project == "" {
+			buffer.WriteString(" " + node)
+			continue
+		}
+		if _, exists := byProject[project]; !exists {
+			projects = append(projects, project)
+		}
+		byProject[project] = append(byProject[project], node)
+	}
+
+	sort.Strings(projects)
+	for _, project := range projects {
+		buffer.WriteString(fmt.Sprintf(" subgraph \"cluster_%s\" {\n", project))
+		buffer.WriteString(fmt.Sprintf("  label=\"%s\";\n", project))
+		buffer.WriteString("  style=\"rounded\";\n")
+		for _, node := range byProject[project] {
+			buffer.WriteString("  " + node)
+		}
+		buffer.WriteString(" }\n")
+	}
+
+	writeContainerLegend(&buffer, usedStyles)
+
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+// jsonContainersToTree groups containers under the image they run, with
+// each link the container has as a child entry.
+func jsonContainersToTree(containers *[]Container, OnlyRunning bool, NoTruncate bool, ShowMounts bool) string {
+	var buffer bytes.Buffer
+
+	links := make(map[string][]ContainerLink)
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		links[link.Target] = append(links[link.Target], link)
+	}
+
+	shares := make(map[string][]ContainerShare)
+	for _, share := range collectContainerShares(containers, OnlyRunning) {
+		shares[share.Target] = append(shares[share.Target], share)
+	}
+
+	_, attachments := collectContainerNetworks(containers, OnlyRunning)
+	networks := make(map[string][]NetworkAttachment)
+	for _, attachment := range attachments {
+		networks[attachment.Container] = append(networks[attachment.Container], attachment)
+	}
+
+	mounts := make(map[string][]MountUse)
+	if ShowMounts {
+		_, uses := collectContainerMounts(containers, OnlyRunning)
+		for _, use := range uses {
+			mounts[use.Container] = append(mounts[use.Container], use)
+		}
+	}
+
+	var images []string
+	byImage := make(map[string][]Container)
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		if _, exists := byImage[container.Image]; !exists {
+			images = append(images, container.Image)
+		}
+		byImage[container.Image] = append(byImage[container.Image], container)
+	}
+	sort.Strings(images)
+
+	var nodes []TextNode
+	for _, image := range images {
+		imageNode := TextNode{Line: image}
+		for _, container := range byImage[image] {
+			containerName := primaryContainerName(container)
+
+			containerID := container.Id
+			if !NoTruncate {
+				containerID = truncate(containerID, 12)
+			}
+
+			line := fmt.Sprintf("%s %s %s", containerName, containerID, container.Status)
+			if ports := formatPorts(container.Ports); ports != "" {
+				line = line + " Ports: " + ports
+			}
+			if size := containerSizeLabel(container); size != "" {
+				line = line + " " + size
+			}
+			if container.Stats != nil {
+				line = line + " " + strings.Join(statsLabelParts(container.Stats), " ")
+			}
+			if risk := containerRiskLabel(container); risk != "" {
+				line = line + " " + risk
+			}
+
+			containerNode := TextNode{Line: line}
+			for _, link := range links[containerName] {
+				containerNode.Children = append(containerNode.Children, TextNode{Line: fmt.Sprintf("%s -> %s", link.Alias, link.Source)})
+			}
+			for _, share := range shares[containerName] {
+				containerNode.Children = append(containerNode.Children, TextNode{Line: fmt.Sprintf("%s -> %s", share.label(), share.Source)})
+			}
+			for _, attachment := range networks[containerName] {
+				containerNode.Children = append(containerNode.Children, TextNode{Line: strings.Join(append([]string{"network " + attachment.Network}, attachment.labelParts()...), " ")})
+			}
+			for _, use := range mounts[containerName] {
+				source := use.Type
+				if use.Type != "tmpfs" {
+					source = source + " " + use.SourceLabel
+				}
+				containerNode.Children = append(containerNode.Children, TextNode{Line: fmt.Sprintf("%s -> %s", source, use.label())})
+			}
+			imageNode.Children = append(imageNode.Children, containerNode)
+		}
+		nodes = append(nodes, imageNode)
+	}
+
+	writeTextTree(&buffer, nodes, "")
+
+	return buffer.String()
+}
+
+func jsonContainersToJSON(containers *[]Container, OnlyRunning bool) (string, error) {
+	links := make(map[string][]ContainerLink)
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		links[link.Target] = append(links[link.Target], link)
+	}
+
+	shares := make(map[string][]ContainerShare)
+	for _, share := range collectContainerShares(containers, OnlyRunning) {
+		shares[share.Target] = append(shares[share.Target], share)
+	}
+
+	nodes := []ContainerNode{}
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		containerName := primaryContainerName(container)
+
+		var ports []map[string]interface{}
+		for _, port := range container.Ports {
+			if port != nil {
+				ports = append(ports, port)
+			}
+		}
+
+		nodes = append(nodes, ContainerNode{
+			Id:         container.Id,
+			Name:       containerName,
+			Names:      container.Names,
+			Image:      container.Image,
+			Status:     container.Status,
+			Command:    container.Command,
+			Created:    container.Created,
+			Ports:      ports,
+			Links:      links[containerName],
+			Shares:     shares[containerName],
+			Networks:   container.NetworkSettings.Networks,
+			Mounts:     container.Mounts,
+			Labels:     container.Labels,
+			Stats:      container.Stats,
+			SizeRw:     container.SizeRw,
+			SizeRootFs: container.SizeRootFs,
+			Risks:      containerRisks(container),
+		})
+	}
+
+	result, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Error writing JSON: %s", err)
+	}
+
+	return string(result) + "\n", nil
+}
+
+// jsonContainersToComposeTree nests containers under their Compose project
+// and service, with containers started outside Compose listed at the end.
+func jsonContainersToComposeTree(containers *[]Container, OnlyRunning bool, NoTruncate bool) string {
+	var buffer bytes.Buffer
+
+	var projects []string
+	var standalone []TextNode
+	services := make(map[string][]string)
+	byService := make(map[string][]TextNode)
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		containerID := container.Id
+		if !NoTruncate {
+			containerID = truncate(containerID, 12)
+		}
+		node := TextNode{Line: fmt.Sprintf("%s %s %s", primaryContainerName(container), containerID, container.Status)}
+		if risk := containerRiskLabel(container); risk != "" {
+			node.Line = node.Line + " " + risk
+		}
+
+		project := container.Labels[composeProjectLabel]
+		if project == "" {
+			standalone = append(standalone, node)
+			continue
+		}
+		if _, exists := services[project]; !exists {
+			projects = append(projects, project)
+		}
+
+		service := container.Labels[composeServiceLabel]
+		key := project + "/" + service
+		if _, exists := byService[key]; !exists {
+			services[project] = append(services[project], service)
+		}
+		if replica := container.Labels[composeNumberLabel]; replica != "" {
+			node.Line = "#" + replica + " " + node.Line
+		}
+		byService[key] = append(byService[key], node)
+	}
+
+	var nodes []TextNode
+	sort.Strings(projects)
+	for _, project := range projects {
+		projectNode := TextNode{Line: project}
+		sort.Strings(services[project])
+		for _, service := range services[project] {
+			serviceNode := TextNode{Line: service, Children: byService[project+"/"+service]}
+			projectNode.Children = append(projectNode.Children, serviceNode)
+		}
+		nodes = append(nodes, projectNode)
+	}
+	if len(standalone) > 0 {
+		nodes = append(nodes, TextNode{Line: "(no project)", Children: standalone})
+	}
+
+	writeTextTree(&buffer, nodes, "")
+
+	return buffer.String()
+}
+
+// sortedContainers returns the containers to show, ordered by name.
+func sortedContainers(containers *[]Container, OnlyRunning bool) []Container {
+	var sorted []Container
+	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
+		sorted = append(sorted, container)
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return primaryContainerName(sorted[a]) < primaryContainerName(sorted[b])
+	})
+	return sorted
+}
+
+func jsonContainersToMermaid(containers *[]Container, OnlyRunning bool) string {
+
+	var buffer bytes.Buffer
+	buffer.WriteString("flowchart TD\n")
+	buffer.WriteString(" classDef running fill:paleturquoise\n")
+	buffer.WriteString(" classDef exited fill:lightgrey\n")
+
+	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
+
+		containerName := primaryContainerName(container)
+
+		var containerClass string
+		if strings.Contains(container.Status, "Exited") {
+			containerClass = "exited"
+		} else {
+			containerClass = "running"
+		}
+
+		buffer.WriteString(fmt.Sprintf(" %s(\"%s\"):::%s\n", mermaidID("c", containerName), mermaidLabel(containerLabelParts(container)), containerClass))
+	}
+
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		buffer.WriteString(fmt.Sprintf(" %s -->|\"%s\"| %s\n", mermaidID("c", link.Source), mermaidLabel([]string{link.Alias}), mermaidID("c", link.Target)))
+	}
+
+	return buffer.String()
+}
+
+func jsonContainersToSVG(containers *[]Container, OnlyRunning bool, ShowMounts bool) string {
+	var graph Graph
+	heat := containerHeat(*containers)
+
+	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
+
+		containerName := primaryContainerName(container)
+
+		fill := containerStyle(container).Fill
+		if container.Stats != nil {
+			fill = heatColor(heat[container.Id])
+		}
+		graph.AddNode(GraphNode{containerName, containerLabelParts(container), fill, true})
+	}
+
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		graph.AddEdge(GraphEdge{link.Source, link.Target, link.Alias})
+	}
+
+	for _, share := range collectContainerShares(containers, OnlyRunning) {
+		graph.AddEdge(GraphEdge{share.Source, share.Target, share.label()})
+	}
+
+	networks, attachments := collectContainerNetworks(containers, OnlyRunning)
+	for _, network := range networks {
+		graph.AddNode(GraphNode{"network:" + network, []string{network}, "khaki", false})
+	}
+	for _, attachment := range attachments {
+		graph.AddEdge(GraphEdge{attachment.Container, "network:" + attachment.Network, strings.Join(attachment.labelParts(), " ")})
+	}
+
+	if ShowMounts {
+		sources, uses := collectContainerMounts(containers, OnlyRunning)
+		for _, source := range sources {
+			graph.AddNode(GraphNode{source.Id, []string{source.Type, source.Label}, mountColors[source.Type], false})
+		}
+		for _, use := range uses {
+			graph.AddEdge(GraphEdge{use.Container, use.Source, use.label()})
+		}
+	}
+
+	return graphToSVG(&graph)
+}
+
+func jsonContainersToAttributeGraph(containers *[]Container, OnlyRunning bool) *AttributeGraph {
+	graph := &AttributeGraph{
+		NodeAttrs: []GraphAttr{
+			{"Id", "string"},
+			{"Image", "string"},
+			{"Names", "string"},
+			{"Status", "string"},
+			{"Command", "string"},
+			{"Created", "long"},
+			{"Ports", "string"},
+			{"SizeRw", "long"},
+			{"SizeRootFs", "long"},
+			{"Risks", "string"},
+		},
+		EdgeAttrs: []GraphAttr{
+			{"Alias", "string"},
+		},
+	}
+
+	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
+
+		containerName := primaryContainerName(container)
+
+		graph.AddNode(containerName, containerName,
+			container.Id,
+			container.Image,
+			strings.Join(container.Names, ", "),
+			container.Status,
+			container.Command,
+			strconv.FormatInt(container.Created, 10),
+			formatPorts(container.Ports),
+			strconv.FormatInt(container.SizeRw, 10),
+			strconv.FormatInt(container.SizeRootFs, 10),
+			containerRiskList(container),
+		)
+	}
+
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		graph.AddEdge(link.Source, link.Target, link.Alias)
+	}
+
+	return graph
+}
+
+// formatPorts describes ports like docker ps does, e.g. "0.0.0.0:8080->80/tcp".
+func formatPorts(ports []map[string]interface{}) string {
+	var formatted []string
+	for _, port := range ports {
+		if port == nil {
+			continue
+		}
+
+		public, hasPublic := port["PublicPort"]
+		hasPublic = hasPublic && public != nil && fmt.Sprint(public) != "0"
+		private, hasPrivate := port["PrivatePort"]
+		hasPrivate = hasPrivate && private != nil
+
+		var description string
+		if hasPublic {
+			if ip, ok := port["IP"]; ok && ip != nil && ip != "" {
+				description = fmt.Sprintf("%v:", ip)
+			}
+			description = description + fmt.Sprint(public)
+			if hasPrivate {
+				description = description + "->"
+			}
+		}
+		if hasPrivate {
+			description = description + fmt.Sprint(private)
+		}
+		if portType, ok := port["Type"]; ok && portType != nil {
+			description = description + fmt.Sprintf("/%v", portType)
+		}
+
+		formatted = append(formatted, description)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// collectContainerLinks finds the legacy links between containers, which
+// show up as extra "/source/alias" entries in the linked container's Names.
+func collectContainerLinks(containers *[]Container, OnlyRunning bool) []ContainerLink {
+
 	// build list of all primary container names
-	// this is so we can throw away links to 
-	// non-primary container name
+	// this is so we can throw away links to
+	// non-primary container name (or to containers
+	// that are not being shown)
 	var PrimaryContainerNames map[string]string
 	PrimaryContainerNames = make(map[string]string)
 	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
 		for _, name := range container.Names {
 			if strings.Count(name, "/") == 1 {
 				PrimaryContainerNames[name[1:]] = name[1:]
@@ -138,52 +849,242 @@ func jsonContainersToDot(containers *[]Container,OnlyRunning bool) string {
 	var LinkMap map[string]string
 	LinkMap = make(map[string]string)
 
+	var links []ContainerLink
 	for _, container := range *containers {
-		if OnlyRunning && strings.HasPrefix(container.Status,"Exit") { continue }
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
 
-		var containerName string
+		containerName := primaryContainerName(container)
 
-		//fmt.Printf("container status/Names %s/%s\n",container.Status,container.Names)
-		for _, name := range container.Names {
-			if strings.Count(name, "/") == 1 {
-				containerName = name[1:]
-			}
-		}
-	
 		for _, name := range container.Names {
 			nameParts := strings.Split(name, "/")
 			if len(nameParts) > 2 {
-				//fmt.Printf("\t%s to %s\n",containerName,nameParts[1])
 				// source and dest should be primary container names
-				if IsPrimaryContainerName(containerName,PrimaryContainerNames) && IsPrimaryContainerName(nameParts[1],PrimaryContainerNames) {
+				if IsPrimaryContainerName(containerName, PrimaryContainerNames) && IsPrimaryContainerName(nameParts[1], PrimaryContainerNames) {
 
-				   // only create link if none exists already
-				   if _,ok := LinkMap[containerName + "-" + nameParts[1]]; !ok {
-				    LinkMap[containerName + "-" + nameParts[1]] = "exists"
-				    buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"%s\" [label = \" %s\" ]\n", containerName, nameParts[1], nameParts[len(nameParts)-1] ))
-				  }
+					// only create link if none exists already
+					if _, ok := LinkMap[containerName+"-"+nameParts[1]]; !ok {
+						LinkMap[containerName+"-"+nameParts[1]] = "exists"
+						links = append(links, ContainerLink{containerName, nameParts[1], nameParts[len(nameParts)-1]})
+					}
 				}
+			}
+		}
+	}
+
+	return links
+}
 
+type NetworkAttachment struct {
+	Container string
+	Network   string
+	IPAddress string
+	Aliases   []string
+}
+
+func (a NetworkAttachment) labelParts() []string {
+	var parts []string
+	if a.IPAddress != "" {
+		parts = append(parts, a.IPAddress)
+	}
+	if len(a.Aliases) > 0 {
+		parts = append(parts, "("+strings.Join(a.Aliases, ", ")+")")
+	}
+	return parts
+}
+
+// collectContainerNetworks finds the user-defined networks the shown
+// containers are attached to, sorted by name, along with each attachment.
+// Aliases that just repeat the container name or ID are left out.
+func collectContainerNetworks(containers *[]Container, OnlyRunning bool) ([]string, []NetworkAttachment) {
+	var networks []string
+	var attachments []NetworkAttachment
+	seen := make(map[string]bool)
+
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		containerName := primaryContainerName(container)
+
+		var names []string
+		for name := range container.NetworkSettings.Networks {
+			if !defaultNetworks[name] {
+				names = append(names, name)
 			}
 		}
+		sort.Strings(names)
 
-		var containerBackground string
-		if strings.Contains(container.Status, "Exited") {
-			containerBackground = "lightgrey"
-		} else {
-			containerBackground = "paleturquoise"
+		for _, name := range names {
+			network := container.NetworkSettings.Networks[name]
+			if !seen[name] {
+				seen[name] = true
+				networks = append(networks, name)
+			}
+
+			var aliases []string
+			for _, alias := range network.Aliases {
+				if alias != containerName && !strings.HasPrefix(container.Id, alias) {
+					aliases = append(aliases, alias)
+				}
+			}
+			attachments = append(attachments, NetworkAttachment{containerName, name, network.IPAddress, aliases})
 		}
+	}
+	sort.Strings(networks)
 
-		buffer.WriteString(fmt.Sprintf(" \"%s\" [label=\"%s\\n%s\\n%s\",shape=box,fillcolor=\"%s\",style=\"filled,rounded\"];\n", containerName, container.Image, containerName, truncate(container.Id, 12), containerBackground))
+	return networks, attachments
+}
+
+var mountShapes = map[string]string{"volume": "cylinder", "bind": "folder", "tmpfs": "note"}
+
+var mountColors = map[string]string{"volume": "wheat", "bind": "lightyellow", "tmpfs": "white"}
+
+// MountSource is something mounted into containers: a named volume, a host
+// path or, since they are never shared, one container's tmpfs mount.
+type MountSource struct {
+	Id    string
+	Type  string
+	Label string
+}
+
+type MountUse struct {
+	Container   string
+	Source      string
+	Type        string
+	SourceLabel string
+	Destination string
+	RW          bool
+}
+
+func (u MountUse) label() string {
+	if u.RW {
+		return u.Destination
 	}
+	return u.Destination + " (ro)"
+}
 
-	buffer.WriteString("}\n")
+// collectContainerMounts finds what the shown containers mount, sorted by
+// type and label, so containers sharing a volume or host path point at the
+// same source.
+func collectContainerMounts(containers *[]Container, OnlyRunning bool) ([]MountSource, []MountUse) {
+	var sources []MountSource
+	var uses []MountUse
+	seen := make(map[string]bool)
 
-	return buffer.String()
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		containerName := primaryContainerName(container)
+
+		for _, mount := range container.Mounts {
+			mountType := mount.Type
+			if mountType == "" {
+				// older daemons leave out the type
+				if mount.Name != "" {
+					mountType = "volume"
+				} else {
+					mountType = "bind"
+				}
+			}
+
+			var source MountSource
+			switch mountType {
+			case "volume":
+				// anonymous volumes are named with a 64 character hash
+				label := mount.Name
+				if len(label) == 64 {
+					label = truncate(label, 12)
+				}
+				source = MountSource{"volume:" + mount.Name, mountType, label}
+			case "tmpfs":
+				source = MountSource{"tmpfs:" + containerName + ":" + mount.Destination, mountType, "tmpfs"}
+			default:
+				source = MountSource{mountType + ":" + mount.Source, mountType, mount.Source}
+			}
+
+			if !seen[source.Id] {
+				seen[source.Id] = true
+				sources = append(sources, source)
+			}
+			uses = append(uses, MountUse{containerName, source.Id, mountType, source.Label, mount.Destination, mount.RW})
+		}
+	}
+
+	sort.SliceStable(sources, func(a, b int) bool {
+		if sources[a].Type != sources[b].Type {
+			return sources[a].Type < sources[b].Type
+		}
+		return sources[a].Label < sources[b].Label
+	})
+
+	return sources, uses
+}
+
+// containerLabelParts returns the lines used to label a container node:
+// what it runs, its Compose service, anything wrong with it, its size and
+// its resource use, as far as they are known.
+func containerLabelParts(container Container) []string {
+	labelParts := []string{container.Image, primaryContainerName(container), truncate(container.Id, 12)}
+	if service := composeService(container); service != "" {
+		labelParts = append(labelParts, service)
+	}
+	labelParts = append(labelParts, containerProblems(container)...)
+	if size := containerSizeLabel(container); size != "" {
+		labelParts = append(labelParts, size)
+	}
+	if container.Stats != nil {
+		labelParts = append(labelParts, statsLabelParts(container.Stats)...)
+	}
+	if risk := containerRiskLabel(container); risk != "" {
+		labelParts = append(labelParts, risk)
+	}
+	return labelParts
+}
+
+// containerSizeLabel describes the size of the container's writable layer
+// like docker ps -s does, or returns "" if the size wasn't listed.
+func containerSizeLabel(container Container) string {
+	if container.SizeRootFs == 0 && container.SizeRw == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Size: %s (virtual %s)", humanSize(container.SizeRw), humanSize(container.SizeRootFs))
+}
+
+const (
+	composeProjectLabel   = "com.docker.compose.project"
+	composeServiceLabel   = "com.docker.compose.service"
+	composeNumberLabel    = "com.docker.compose.container-number"
+	composeDependsOnLabel = "com.docker.compose.depends_on"
+)
+
+// composeService describes a Compose container as its service and replica,
+// e.g. "web #2", or returns "" for containers not started by Compose.
+func composeService(container Container) string {
+	service := container.Labels[composeServiceLabel]
+	if service == "" {
+		return ""
+	}
+	if replica := container.Labels[composeNumberLabel]; replica != "" {
+		return service + " #" + replica
+	}
+	return service
+}
+
+// primaryContainerName returns the container's own name, not one of the
+// "/other/alias" names links add, or its Id if it has none, since graph
+// nodes are keyed by it and need to be unique.
+func primaryContainerName(container Container) string {
+	var containerName string
+	for _, name := range container.Names {
+		if strings.Count(name, "/") == 1 {
+			containerName = name[1:]
+		}
+	}
+	if containerName == "" {
+		return container.Id
+	}
+	return containerName
 }
 
-func IsPrimaryContainerName(Name string,PrimaryContainerNames map[string]string) bool {
-	_,ok := PrimaryContainerNames[Name]
+func IsPrimaryContainerName(Name string, PrimaryContainerNames map[string]string) bool {
+	_, ok := PrimaryContainerNames[Name]
 	return ok
 }
 
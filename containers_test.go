@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type ContainersTest struct {
+	json        string
+	onlyRunning bool
+	regexps     []string
+	excludes    []string
+}
+
+const containersJSON = `[{"Status":"Exited (0) 9 seconds ago","Ports":[],"Names":["/app2"],"Image":"ubuntu:12.10","Id":"878602c44611115d52118edeb768fc62de8cfed8c3bdb8c5cd2e149cb1c20afa","Created":1399985983,"Command":"/bin/bash"},{"Status":"Up 2 minutes","Ports":[],"Names":["/app1"],"Image":"ubuntu:12.10","Id":"6a2fa6a3c2d43738a1b850a17b3da212970efce83d119da2707177d1e506567f","Created":1399985012,"Command":"/bin/bash"},{"Status":"Up 4 minutes","Ports":[{"Type":"tcp","PublicPort":6379}],"Names":["/app1/db","/app2/db","/redis"],"Image":"redis:latest","Id":"5d7e818a4ea3cf01bdf5e1fdaebf645e11469ba0e55a506cde31834732766421","Created":1399984760,"Command":"/usr/bin/redis-server"}]`
+
+func Test_ContainersDot(t *testing.T) {
+	containersTests := []ContainersTest{
+		ContainersTest{
+			json: containersJSON,
+			regexps: []string{
+				"(?s)digraph docker {.*}",
+				`"redis" -> "app1" \[label = " db" \]`,
+				`"redis" -> "app2" \[label = " db" \]`,
+				`"app2" \[label="ubuntu:12.10\\napp2\\n878602c44611",shape=box,fillcolor="lightgrey"`,
+				`"redis" \[label="redis:latest\\nredis\\n5d7e818a4ea3",shape=box,fillcolor="paleturquoise"`,
+			},
+		},
+	}
+
+	for _, containersTest := range containersTests {
+		containers, _ := parseContainersJSON([]byte(containersTest.json))
+		result := jsonContainersToDot(containers, containersTest.onlyRunning, false)
+
+		for _, regexp := range compileRegexps(t, containersTest.regexps) {
+			if !regexp.MatchString(result) {
+				t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+			}
+		}
+	}
+}
+
+func Test_ContainersMermaid(t *testing.T) {
+	containersTests := []ContainersTest{
+		ContainersTest{
+			json: containersJSON,
+			regexps: []string{
+				`(?m)^flowchart TD$`,
+				`(?m)^ credis -->\|"db"\| capp1$`,
+				`(?m)^ capp2\("ubuntu:12.10<br/>app2<br/>878602c44611"\):::exited$`,
+				`(?m)^ credis\("redis:latest<br/>redis<br/>5d7e818a4ea3"\):::running$`,
+			},
+		},
+		ContainersTest{
+			json:        containersJSON,
+			onlyRunning: true,
+			regexps: []string{
+				`(?m)^ credis -->\|"db"\| capp1$`,
+			},
+			excludes: []string{
+				"capp2",
+			},
+		},
+	}
+
+	for _, containersTest := range containersTests {
+		containers, _ := parseContainersJSON([]byte(containersTest.json))
+		result := jsonContainersToMermaid(containers, containersTest.onlyRunning)
+
+		for _, regexp := range compileRegexps(t, containersTest.regexps) {
+			if !regexp.MatchString(result) {
+				t.Fatalf("containers mermaid content '%s' did not match regexp '%s'", result, regexp)
+			}
+		}
+
+		for _, exclude := range containersTest.excludes {
+			if strings.Contains(result, exclude) {
+				t.Fatalf("containers mermaid content '%s' should not contain '%s'", result, exclude)
+			}
+		}
+	}
+}
+
+func Test_ContainersSVG(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+	result := jsonContainersToSVG(containers, false, false)
+
+	for _, regexp := range compileRegexps(t, []string{
+		`(?s)<g id="app2">\s*<rect [^>]*fill="lightgrey"`,
+		`(?s)<g id="redis">\s*<rect [^>]*fill="paleturquoise"[^>]*/>\s*<text [^>]*>redis:latest</text>`,
+		`<text x="[0-9.]+" y="[0-9.]+">db</text>`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers svg content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+func Test_ContainersTree(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+
+	result := jsonContainersToTree(containers, false, false, false)
+	expected := "├─redis:latest\n" +
+		"│ └─redis 5d7e818a4ea3 Up 4 minutes Ports: 6379/tcp\n" +
+		"└─ubuntu:12.10\n" +
+		"  ├─app1 6a2fa6a3c2d4 Up 2 minutes\n" +
+		"  │ └─db -> redis\n" +
+		"  └─app2 878602c44611 Exited (0) 9 seconds ago\n" +
+		"    └─db -> redis\n"
+	if result != expected {
+		t.Fatalf("containers tree content '%s' did not match '%s'", result, expected)
+	}
+
+	result = jsonContainersToTree(containers, true, true, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^  └─app1 6a2fa6a3c2d43738a1b850a17b3da212970efce83d119da2707177d1e506567f Up 2 minutes$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	if strings.Contains(result, "app2") {
+		t.Fatalf("containers tree content '%s' should not contain exited containers", result)
+	}
+}
+
+func Test_ContainersJSON(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+
+	result, err := jsonContainersToJSON(containers, false)
+	if err != nil {
+		t.Fatalf("containers json failed: %s", err)
+	}
+
+	var nodes []ContainerNode
+	if err := json.Unmarshal([]byte(result), &nodes); err != nil {
+		t.Fatalf("containers json content '%s' is not valid json: %s", result, err)
+	}
+	if len(nodes) != 3 || nodes[0].Name != "app1" || nodes[2].Name != "redis" {
+		t.Fatalf("containers json content '%s' is not sorted by name", result)
+	}
+	if len(nodes[0].Links) != 1 || nodes[0].Links[0].Source != "redis" || nodes[0].Links[0].Alias != "db" {
+		t.Fatalf("containers json node '%+v' is missing its link", nodes[0])
+	}
+	if len(nodes[2].Ports) != 1 || nodes[2].Ports[0]["Type"] != "tcp" {
+		t.Fatalf("containers json node '%+v' is missing its port", nodes[2])
+	}
+}
+
+const networkedContainersJSON = `[{"Status":"Up 1 minute","Names":["/shop_web_1"],"Image":"nginx:latest","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"nginx","NetworkSettings":{"Networks":{"bridge":{"IPAddress":"172.17.0.2"},"shop_front":{"IPAddress":"172.20.0.2","Aliases":["shop_web_1","web","1c0e4a3d5b2f"]}}}},{"Status":"Up 1 minute","Names":["/shop_db_1"],"Image":"postgres:15","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"postgres","NetworkSettings":{"Networks":{"shop_back":{"IPAddress":"172.21.0.3","Aliases":["db"]}}}},{"Status":"Up 1 minute","Names":["/shop_api_1"],"Image":"shop/api:1.0","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399984760,"Command":"api","NetworkSettings":{"Networks":{"shop_front":{"IPAddress":"172.20.0.3","Aliases":["api"]},"shop_back":{"IPAddress":"172.21.0.2","Aliases":["api"]}}}}]`
+
+func Test_ContainersNetworks(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(networkedContainersJSON))
+
+	result := jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "network:shop_front" \[label="shop_front",shape=ellipse`,
+		`(?m)^ "network:shop_back" \[label="shop_back",shape=ellipse`,
+		`(?m)^ "shop_web_1" -> "network:shop_front" \[label = " 172.20.0.2\\n\(web\)",dir=none,style=dashed \]$`,
+		`(?m)^ "shop_api_1" -> "network:shop_back" \[label = " 172.21.0.2\\n\(api\)",dir=none,style=dashed \]$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	if strings.Contains(result, "network:bridge") {
+		t.Fatalf("containers dot content '%s' should not contain the default bridge network", result)
+	}
+
+	result = jsonContainersToTree(containers, false, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^    ├─network shop_back 172.21.0.2 \(api\)$`,
+		`(?m)^│   └─network shop_front 172.20.0.2 \(web\)$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+const mountedContainersJSON = `[{"Status":"Up 1 minute","Names":["/db"],"Image":"postgres:15","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"postgres","Mounts":[{"Type":"volume","Name":"pgdata","Source":"/var/lib/docker/volumes/pgdata/_data","Destination":"/var/lib/postgresql/data","RW":true},{"Type":"bind","Source":"/etc/shop/pg.conf","Destination":"/etc/postgresql/postgresql.conf","RW":false},{"Type":"tmpfs","Destination":"/run","RW":true}]},{"Status":"Exited (0) 1 minute ago","Names":["/backup"],"Image":"shop/backup:1.0","Id":"4f3b7d6a8e5c2d1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a09","Created":1399984760,"Command":"backup","Mounts":[{"Type":"volume","Name":"pgdata","Source":"/var/lib/docker/volumes/pgdata/_data","Destination":"/data","RW":false},{"Type":"volume","Name":"9c1ad0f5e4b3a29187766554433221100ffeeddccbbaa9988776655443322110","Destination":"/tmp/work","RW":true}]}]`
+
+func Test_ContainersMounts(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(mountedContainersJSON))
+
+	result := jsonContainersToDot(containers, false, true)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "volume:pgdata" \[label="pgdata",shape=cylinder`,
+		`(?m)^ "volume:9c1ad0f5e4b3a29187766554433221100ffeeddccbbaa9988776655443322110" \[label="9c1ad0f5e4b3",shape=cylinder`,
+		`(?m)^ "bind:/etc/shop/pg.conf" \[label="/etc/shop/pg.conf",shape=folder`,
+		`(?m)^ "tmpfs:db:/run" \[label="tmpfs",shape=note`,
+		`(?m)^ "db" -> "volume:pgdata" \[label = " /var/lib/postgresql/data",style=solid \]$`,
+		`(?m)^ "backup" -> "volume:pgdata" \[label = " /data \(ro\)",style=dotted \]$`,
+		`(?m)^ "db" -> "bind:/etc/shop/pg.conf" \[label = " /etc/postgresql/postgresql.conf \(ro\)",style=dotted \]$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	if strings.Count(result, "\n \"volume:pgdata\" [") != 1 {
+		t.Fatalf("containers dot content '%s' should have one node for the shared volume", result)
+	}
+
+	result = jsonContainersToDot(containers, true, false)
+	if strings.Contains(result, "volume:") {
+		t.Fatalf("containers dot content '%s' should not contain mounts unless asked", result)
+	}
+
+	result = jsonContainersToDot(containers, true, true)
+	if strings.Contains(result, "/tmp/work") {
+		t.Fatalf("containers dot content '%s' should not contain mounts of exited containers", result)
+	}
+
+	result = jsonContainersToTree(containers, false, false, true)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^    ├─volume pgdata -> /data \(ro\)$`,
+		`(?m)^    └─volume 9c1ad0f5e4b3 -> /tmp/work$`,
+		`(?m)^│   ├─bind /etc/shop/pg.conf -> /etc/postgresql/postgresql.conf \(ro\)$`,
+		`(?m)^│   └─tmpfs -> /run$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+const composeContainersJSON = `[{"Status":"Up 1 minute","Names":["/shop-web-1"],"Image":"nginx:latest","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"nginx","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"web","com.docker.compose.container-number":"1"}},{"Status":"Up 1 minute","Names":["/shop-web-2"],"Image":"nginx:latest","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"nginx","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"web","com.docker.compose.container-number":"2"}},{"Status":"Up 1 minute","Names":["/shop-db-1"],"Image":"postgres:15","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399984760,"Command":"postgres","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"db","com.docker.compose.container-number":"1"}},{"Status":"Up 1 minute","Names":["/blog-app-1"],"Image":"ghost:5","Id":"4f3b7d6a8e5c2d1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a09","Created":1399984000,"Command":"ghost","Labels":{"com.docker.compose.project":"blog","com.docker.compose.service":"app","com.docker.compose.container-number":"1"}},{"Status":"Up 1 minute","Names":["/scratch"],"Image":"ubuntu:22.04","Id":"5a4c8e7b9f6d3e2c1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a","Created":1399983000,"Command":"bash"}]`
+
+func Test_ContainersCompose(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(composeContainersJSON))
+
+	result := jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?s) subgraph "cluster_blog" {\n  label="blog";.*  "blog-app-1" \[label="ghost:5\\nblog-app-1\\n4f3b7d6a8e5c\\napp #1"`,
+		`(?s) subgraph "cluster_shop" {\n  label="shop";.*  "shop-web-2" \[label="nginx:latest\\nshop-web-2\\n2d1f5b4e6c3a\\nweb #2".* }\n}`,
+		`(?m)^ "scratch" \[label="ubuntu:22.04\\nscratch\\n5a4c8e7b9f6d",`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	result = jsonContainersToComposeTree(containers, false, false)
+	expected := "├─blog\n" +
+		"│ └─app\n" +
+		"│   └─#1 blog-app-1 4f3b7d6a8e5c Up 1 minute\n" +
+		"├─shop\n" +
+		"│ ├─db\n" +
+		"│ │ └─#1 shop-db-1 3e2a6c5f7d4b Up 1 minute\n" +
+		"│ └─web\n" +
+		"│   ├─#1 shop-web-1 1c0e4a3d5b2f Up 1 minute\n" +
+		"│   └─#2 shop-web-2 2d1f5b4e6c3a Up 1 minute\n" +
+		"└─(no project)\n" +
+		"  └─scratch 5a4c8e7b9f6d Up 1 minute\n"
+	if result != expected {
+		t.Fatalf("containers compose tree content '%s' did not match '%s'", result, expected)
+	}
+}
+
+func Test_ContainersUnnamed(t *testing.T) {
+	unnamedJSON := `[{"Status":"Up 1 minute","Names":[],"Image":"busybox:latest","Id":"a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","Created":1399985983,"Command":"sh"},{"Status":"Up 1 minute","Image":"busybox:latest","Id":"b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1","Created":1399985984,"Command":"sh"}]`
+	containers, _ := parseContainersJSON([]byte(unnamedJSON))
+
+	graph := Graph{}
+	for _, container := range *containers {
+		graph.AddNode(GraphNode{Id: primaryContainerName(container), Label: []string{container.Image}})
+	}
+	layout := layoutGraph(&graph)
+	if len(layout.Nodes) != 2 || layout.Nodes[0].Node.Id == layout.Nodes[1].Node.Id || layout.Nodes[0].X == layout.Nodes[1].X {
+		t.Fatalf("unnamed containers were not laid out as separate nodes: %+v", layout.Nodes)
+	}
+
+	result := jsonContainersToSVG(containers, false, false)
+	for _, id := range []string{"a1a1a1a1a1a1", "b2b2b2b2b2b2"} {
+		if !strings.Contains(result, id) {
+			t.Fatalf("containers svg content '%s' did not include '%s'", result, id)
+		}
+	}
+}
+
+// Test_ContainersDaemon lists and inspects containers from a stand-in
+// daemon, keeping the listing's order and skipping containers removed in
+// between.
+func Test_ContainersDaemon(t *testing.T) {
+	responses := map[string]string{
+		"/containers/json": `[{"Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Names":["/web"],"Image":"nginx:latest","Status":"Up 1 minute"},{"Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Names":["/gone"],"Image":"busybox:latest","Status":"Up 1 minute"},{"Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Names":["/api"],"Image":"shop/api:1.0","Status":"Up 1 minute"}]`,
+		"/containers/1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706/json": `{"Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Image":"sha256:c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","HostConfig":{"NetworkMode":"bridge"}}`,
+		"/containers/2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807/json": `{"Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Image":"sha256:735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","HostConfig":{"NetworkMode":"container:web"}}`,
+	}
+	client := newStandInDaemon(t, responses, nil)
+	containers, err := listContainers(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := containerNames(containers); strings.Join(names, ",") != "web,api" {
+		t.Fatalf("daemon containers '%v' were not 'web,api'", names)
+	}
+	api := (*containers)[1]
+	if api.ImageID != "sha256:735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470" || api.HostConfig.NetworkMode != "container:web" {
+		t.Fatalf("daemon container '%+v' was not filled in from inspecting it", api)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filters holds --filter values by key.  As with docker, values for the
+// same key are alternatives (except labels, which must all be present) and
+// different keys must all match.
+type Filters map[string][]string
+
+var containerFilterKeys = []string{"label", "name", "status", "ancestor", "before", "since"}
+
+var imageFilterKeys = []string{"label", "before", "since", "reference", "dangling"}
+
+func parseFilters(specs []string, available []string) (Filters, error) {
+	filters := make(Filters)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("Bad filter '%s', filters look like key=value", spec)
+		}
+
+		found := false
+		for _, key := range available {
+			if key == parts[0] {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown filter '%s', choose from: %s", parts[0], strings.Join(available, ", "))
+		}
+
+		filters[parts[0]] = append(filters[parts[0]], parts[1])
+	}
+	return filters, nil
+}
+
+// matchAny checks a filter key, which passes when the key isn't used or
+// any of its values match.
+func (f Filters) matchAny(key string, match func(value string) bool) bool {
+	values, exists := f[key]
+	if !exists {
+		return true
+	}
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filters) matchAll(key string, match func(value string) bool) bool {
+	for _, value := range f[key] {
+		if !match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterContainers applies container filters.  With images given,
+// ancestor= also matches containers of images built on the one named, as
+// docker's does; without them only the container's own image is compared.
+func filterContainers(containers *[]Container, filters Filters, images *[]Image) (*[]Container, error) {
+	if len(filters) == 0 {
+		return containers, nil
+	}
+
+	ancestors := make(map[string][]Image)
+	if images != nil && len(filters["ancestor"]) > 0 {
+		ancestors = collectAncestors(containers, images)
+	}
+
+	// before and since are relative to another container's creation
+	created := func(reference string) (int64, error) {
+		for _, container := range *containers {
+			if primaryContainerName(container) == reference || strings.HasPrefix(container.Id, reference) {
+				return container.Created, nil
+			}
+		}
+		return 0, fmt.Errorf("Unable to find container %s.", reference)
+	}
+	createdBounds := make(map[string]int64)
+	for _, key := range []string{"before", "since"} {
+		for _, reference := range filters[key] {
+			when, err := created(reference)
+			if err != nil {
+				return nil, err
+			}
+			createdBounds[key+"="+reference] = when
+		}
+	}
+
+	var names []*regexp.Regexp
+	for _, name := range filters["name"] {
+		pattern, err := regexp.Compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("Bad name filter '%s': %s", name, err)
+		}
+		names = append(names, pattern)
+	}
+
+	var filtered []Container
+	for _, container := range *containers {
+		matched := filters.matchAll("label", func(value string) bool {
+			return matchLabel(container.Labels, value)
+		}) && filters.matchAny("status", func(value string) bool {
+			return containerState(container.Status) == value
+		}) && filters.matchAny("ancestor", func(value string) bool {
+			if matchReference(container.Image, value) || matchImageID(container.ImageID, value) {
+				return true
+			}
+			for _, image := range ancestors[container.Id] {
+				if matchImage(image, value) {
+					return true
+				}
+			}
+			return false
+		}) && filters.matchAny("before", func(value string) bool {
+			return container.Created < createdBounds["before="+value]
+		}) && filters.matchAny("since", func(value string) bool {
+			return container.Created > createdBounds["since="+value]
+		})
+
+		if matched && len(names) > 0 {
+			matched = false
+			for _, pattern := range names {
+				if pattern.MatchString(primaryContainerName(container)) {
+					matched = true
+				}
+			}
+		}
+
+		if matched {
+			filtered = append(filtered, container)
+		}
+	}
+
+	return &filtered, nil
+}
+
+// collectAncestors finds the layers each container's image is built on,
+// starting with the image itself.
+func collectAncestors(containers *[]Container, images *[]Image) map[string][]Image {
+	byId := make(map[string]Image)
+	for _, image := range *images {
+		byId[image.Id] = image
+	}
+
+	ancestors := make(map[string][]Image)
+	for imageId, imageContainers := range containersByImage(images, containers) {
+		var chain []Image
+		seen := make(map[string]bool)
+		for id := imageId; id != "" && !seen[id]; id = byId[id].ParentId {
+			seen[id] = true
+			if image, exists := byId[id]; exists {
+				chain = append(chain, image)
+			}
+		}
+		for _, container := range imageContainers {
+			ancestors[container.Id] = chain
+		}
+	}
+	return ancestors
+}
+
+// matchImage checks whether a reference names an image, by tag or by ID
+// prefix.
+func matchImage(image Image, reference string) bool {
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" && matchReference(tag, reference) {
+			return true
+		}
+	}
+	return matchImageID(image.OrigId, reference)
+}
+
+var imageIDRegexp = regexp.MustCompile(`^[0-9a-f]{12,64}$`)
+
+// matchImageID checks whether a reference is an ID prefix of an image.  It
+// has to look like an ID, written with "sha256:" or at least 12 hex digits,
+// so a tag such as postgres:15 isn't taken for one.
+func matchImageID(id string, reference string) bool {
+	if id == "" {
+		return false
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		reference = strings.TrimPrefix(reference, "sha256:")
+	} else if !imageIDRegexp.MatchString(reference) {
+		return false
+	}
+	return reference != "" && strings.HasPrefix(stripPrefix(id), reference)
+}
+
+// filterImageList keeps the images matching the filters, along with the
+// layers they are built on so that the tree stays connected.
+func filterImageList(images *[]Image, filters Filters) (*[]Image, error) {
+	if len(filters) == 0 {
+		return images, nil
+	}
+
+	createdBounds := make(map[string]int64)
+	for _, key := range []string{"before", "since"} {
+		for _, reference := range filters[key] {
+			image, err := findStartImage(reference, images)
+			if err != nil {
+				return nil, err
+			}
+			createdBounds[key+"="+reference] = image.Created
+		}
+	}
+
+	hasChildren := make(map[string]bool)
+	byId := make(map[string]Image)
+	for _, image := range *images {
+		hasChildren[image.ParentId] = true
+		byId[image.Id] = image
+	}
+
+	keep := make(map[string]bool)
+	for _, image := range *images {
+		tagged := image.RepoTags[0] != "<none>:<none>"
+
+		matched := filters.matchAll("label", func(value string) bool {
+			return matchLabel(image.Labels, value)
+		}) && filters.matchAny("reference", func(value string) bool {
+			for _, tag := range image.RepoTags {
+				if tagged && matchReferencePattern(tag, value) {
+					return true
+				}
+			}
+			return false
+		}) && filters.matchAny("dangling", func(value string) bool {
+			dangling := !tagged && !hasChildren[image.Id]
+			return (value == "true" || value == "1") == dangling
+		}) && filters.matchAny("before", func(value string) bool {
+			return image.Created < createdBounds["before="+value]
+		}) && filters.matchAny("since", func(value string) bool {
+			return image.Created > createdBounds["since="+value]
+		})
+
+		if matched {
+			for id := image.Id; id != "" && !keep[id]; id = byId[id].ParentId {
+				keep[id] = true
+			}
+		}
+	}
+
+	var filtered []Image
+	for _, image := range *images {
+		if keep[image.Id] {
+			filtered = append(filtered, image)
+		}
+	}
+
+	return &filtered, nil
+}
+
+// matchLabel checks for a label ("key") or a label with a value
+// ("key=value").
+func matchLabel(labels map[string]string, filter string) bool {
+	parts := strings.SplitN(filter, "=", 2)
+	value, exists := labels[parts[0]]
+	if len(parts) == 1 {
+		return exists
+	}
+	return exists && value == parts[1]
+}
+
+// containerState turns the Status docker ps shows back into the container
+// state, e.g. "Up 2 minutes (Paused)" is "paused".
+func containerState(status string) string {
+	switch {
+	case strings.HasPrefix(status, "Up") && strings.Contains(status, "(Paused)"):
+		return "paused"
+	case strings.HasPrefix(status, "Up"):
+		return "running"
+	case strings.HasPrefix(status, "Exit"):
+		return "exited"
+	case strings.HasPrefix(status, "Restarting"):
+		return "restarting"
+	case strings.HasPrefix(status, "Removal"):
+		return "removing"
+	case strings.HasPrefix(status, "Dead"):
+		return "dead"
+	}
+	return "created"
+}
+
+// matchReference compares two image references, treating a missing tag as
+// "latest".
+func matchReference(image string, reference string) bool {
+	return withTag(image) == withTag(reference)
+}
+
+// matchReferencePattern matches a repo:tag against a reference filter,
+// which may use shell wildcards and may leave out the tag.
+func matchReferencePattern(tag string, pattern string) bool {
+	if matched, _ := path.Match(pattern, tag); matched {
+		return true
+	}
+	if !strings.Contains(pattern[strings.LastIndex(pattern, "/")+1:], ":") && strings.Contains(tag, ":") {
+		repository := tag[:strings.LastIndex(tag, ":")]
+		matched, _ := path.Match(pattern, repository)
+		return matched
+	}
+	return false
+}
+
+func withTag(reference string) string {
+	if !strings.Contains(reference[strings.LastIndex(reference, "/")+1:], ":") {
+		return reference + ":latest"
+	}
+	return reference
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func containerNames(containers *[]Container) []string {
+	var names []string
+	for _, container := range *containers {
+		names = append(names, primaryContainerName(container))
+	}
+	return names
+}
+
+func Test_FilterContainers(t *testing.T) {
+	tests := []struct {
+		filters  []string
+		expected []string
+	}{
+		{[]string{}, []string{"shop-web-1", "shop-web-2", "shop-db-1", "blog-app-1", "scratch"}},
+		{[]string{"label=com.docker.compose.project=shop"}, []string{"shop-web-1", "shop-web-2", "shop-db-1"}},
+		{[]string{"label=com.docker.compose.project=shop", "label=com.docker.compose.service=web"}, []string{"shop-web-1", "shop-web-2"}},
+		{[]string{"label=com.docker.compose.service"}, []string{"shop-web-1", "shop-web-2", "shop-db-1", "blog-app-1"}},
+		{[]string{"name=^shop-.*-1$", "name=scratch"}, []string{"shop-web-1", "shop-db-1", "scratch"}},
+		{[]string{"ancestor=nginx"}, []string{"shop-web-1", "shop-web-2"}},
+		{[]string{"ancestor=postgres:15", "status=running"}, []string{"shop-db-1"}},
+		{[]string{"status=exited"}, nil},
+		{[]string{"before=shop-db-1"}, []string{"blog-app-1", "scratch"}},
+		{[]string{"since=blog-app-1"}, []string{"shop-web-1", "shop-web-2", "shop-db-1"}},
+	}
+
+	for _, test := range tests {
+		containers, _ := parseContainersJSON([]byte(composeContainersJSON))
+		filters, err := parseFilters(test.filters, containerFilterKeys)
+		if err != nil {
+			t.Fatalf("filters '%v' were rejected: %s", test.filters, err)
+		}
+		filtered, err := filterContainers(containers, filters, nil)
+		if err != nil {
+			t.Fatalf("filters '%v' failed: %s", test.filters, err)
+		}
+
+		names := containerNames(filtered)
+		if len(names) != len(test.expected) {
+			t.Fatalf("filters '%v' gave '%v', expected '%v'", test.filters, names, test.expected)
+		}
+		for i := range names {
+			if names[i] != test.expected[i] {
+				t.Fatalf("filters '%v' gave '%v', expected '%v'", test.filters, names, test.expected)
+			}
+		}
+	}
+
+	if _, err := parseFilters([]string{"dangling=true"}, containerFilterKeys); err == nil {
+		t.Error("image only filter was accepted for containers")
+	}
+	if _, err := parseFilters([]string{"label"}, containerFilterKeys); err == nil {
+		t.Error("filter without a value did not cause an error")
+	}
+
+	containers, _ := parseContainersJSON([]byte(composeContainersJSON))
+	filters, _ := parseFilters([]string{"before=nothing"}, containerFilterKeys)
+	if _, err := filterContainers(containers, filters, nil); err == nil {
+		t.Error("before an unknown container did not cause an error")
+	}
+}
+
+// Test_FilterAncestor follows the layers of the images containers run, so
+// a base image matches the containers of images built on it.
+func Test_FilterAncestor(t *testing.T) {
+	imagesJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["shop/web:1.0"],"ParentId":"synth:735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"synth:c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","OrigId":"sha256:c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142125},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["nginx:latest"],"ParentId":"synth:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"synth:735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","OrigId":"sha256:735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"synth:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","OrigId":"<missing>","Created":1386114144}]`
+	containersJSON := `[{"Status":"Up 1 minute","Names":["/web"],"Image":"shop/web:1.0","ImageID":"sha256:c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"app"},{"Status":"Up 1 minute","Names":["/proxy"],"Image":"nginx","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985984,"Command":"nginx"},{"Status":"Up 1 minute","Names":["/other"],"Image":"busybox:latest","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399985985,"Command":"sh"}]`
+
+	images, _ := parseImagesJSON([]byte(imagesJSON))
+	tests := []struct {
+		filters  []string
+		images   *[]Image
+		expected []string
+	}{
+		{[]string{"ancestor=nginx"}, images, []string{"web", "proxy"}},
+		{[]string{"ancestor=735f5db56261"}, images, []string{"web", "proxy"}},
+		{[]string{"ancestor=shop/web:1.0"}, images, []string{"web"}},
+		{[]string{"ancestor=nginx"}, nil, []string{"proxy"}},
+		{[]string{"ancestor=sha256:c87be8e5e697"}, nil, []string{"web"}},
+		{[]string{"ancestor=c87be8e5e697"}, nil, []string{"web"}},
+		// the tag is also the start of web's image ID, but isn't an ID
+		{[]string{"ancestor=postgres:c87be8e5"}, nil, nil},
+		{[]string{"ancestor=c87be8e5"}, nil, nil},
+	}
+
+	for _, test := range tests {
+		containers, _ := parseContainersJSON([]byte(containersJSON))
+		filters, _ := parseFilters(test.filters, containerFilterKeys)
+		filtered, err := filterContainers(containers, filters, test.images)
+		if err != nil {
+			t.Fatalf("filters '%v' failed: %s", test.filters, err)
+		}
+
+		names := containerNames(filtered)
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Fatalf("filters '%v' gave '%v', expected '%v'", test.filters, names, test.expected)
+		}
+	}
+}
+
+func Test_FilterImages(t *testing.T) {
+	filterJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["foo:latest","foo:1.0"],"ParentId":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142125,"Labels":{"team":"shop"}},{"VirtualSize":682553464,"Size":20000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"626147582d2ae3735f5db5f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142124},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["registry.local:5000/bar:2.1"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Created":1386114144}]`
+
+	tests := []struct {
+		filters  []string
+		expected []string
+	}{
+		{[]string{"label=team=shop"}, []string{"c87be8e5e697", "735f5db56261", "4c1208b690c6"}},
+		{[]string{"reference=foo:1.*"}, []string{"c87be8e5e697", "735f5db56261", "4c1208b690c6"}},
+		{[]string{"reference=registry.local:5000/bar"}, []string{"735f5db56261", "4c1208b690c6"}},
+		{[]string{"reference=*/bar:2.1"}, []string{"735f5db56261", "4c1208b690c6"}},
+		{[]string{"dangling=true"}, []string{"626147582d2a", "4c1208b690c6"}},
+		{[]string{"dangling=false"}, []string{"c87be8e5e697", "735f5db56261", "4c1208b690c6"}},
+		{[]string{"since=registry.local:5000/bar:2.1"}, []string{"c87be8e5e697", "626147582d2a", "735f5db56261", "4c1208b690c6"}},
+		{[]string{"before=626147582d2a"}, []string{"735f5db56261", "4c1208b690c6"}},
+	}
+
+	for _, test := range tests {
+		images, _ := parseImagesJSON([]byte(filterJSON))
+		filters, err := parseFilters(test.filters, imageFilterKeys)
+		if err != nil {
+			t.Fatalf("filters '%v' were rejected: %s", test.filters, err)
+		}
+		filtered, err := filterImageList(images, filters)
+		if err != nil {
+			t.Fatalf("filters '%v' failed: %s", test.filters, err)
+		}
+
+		var ids []string
+		for _, image := range *filtered {
+			ids = append(ids, truncate(image.Id, 12))
+		}
+		if len(ids) != len(test.expected) {
+			t.Fatalf("filters '%v' gave '%v', expected '%v'", test.filters, ids, test.expected)
+		}
+		for i := range ids {
+			if ids[i] != test.expected[i] {
+				t.Fatalf("filters '%v' gave '%v', expected '%v'", test.filters, ids, test.expected)
+			}
+		}
+	}
+}
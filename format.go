@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ImageView is what a --format template sees for each node of the tree.
+type ImageView struct {
+	ID          string
+	ShortID     string
+	Tags        []string
+	Size        int64
+	VirtualSize int64
+	Created     time.Time
+	CreatedBy   string
+	Depth       int
+	ChildCount  int
+}
+
+// RepoView is what a --format template sees for each line of --short.
+type RepoView struct {
+	Repository string
+	Tags       []string
+}
+
+var formatFuncs = template.FuncMap{
+	"join":      strings.Join,
+	"humanSize": humanSize,
+	"truncate":  truncate,
+	"sanitize":  SanitizeCommand,
+}
+
+// sample views, filled in like a real tagged layer and repository, that
+// formats are tried out on
+var sampleImageView = ImageView{
+	ID:          "sha256:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358",
+	ShortID:     "4c1208b690c6",
+	Tags:        []string{"foo:latest"},
+	Size:        2000000,
+	VirtualSize: 662553464,
+	Created:     time.Unix(1386114144, 0),
+	CreatedBy:   "/bin/sh -c #(nop) CMD [\"sh\"]",
+	Depth:       1,
+	ChildCount:  1,
+}
+
+var sampleRepoView = RepoView{"foo", []string{"latest"}}
+
+// parseFormat parses a --format template and tries it out on a sample
+// view, so mistakes like unknown fields are reported before any output.
+// The sample has every field set, so templates that index or slice them
+// are not rejected; lines they still fail on show the error instead.
+func parseFormat(format string, view interface{}) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid format: %s", err)
+	}
+
+	if err := tmpl.Execute(ioutil.Discard, view); err != nil {
+		return nil, fmt.Errorf("Invalid format: %s", err)
+	}
+
+	return tmpl, nil
+}
+
+func newImageView(image Image, depth int, childCount int) ImageView {
+	var tags []string
+	if image.RepoTags[0] != "<none>:<none>" {
+		tags = image.RepoTags
+	}
+
+	return ImageView{
+		ID:          image.OrigId,
+		ShortID:     truncate(stripPrefix(image.OrigId), 12),
+		Tags:        tags,
+		Size:        image.Size,
+		VirtualSize: image.VirtualSize,
+		Created:     time.Unix(image.Created, 0),
+		CreatedBy:   image.CreatedBy,
+		Depth:       depth,
+		ChildCount:  childCount,
+	}
+}
+
+// executeFormat renders one line, showing any error in place of the line
+// rather than losing the rest of the output.
+func executeFormat(buffer *bytes.Buffer, format *template.Template, view interface{}) {
+	if err := format.Execute(buffer, view); err != nil {
+		buffer.WriteString(fmt.Sprintf("<%s>", err))
+	}
+	buffer.WriteString("\n")
+}
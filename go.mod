@@ -3,6 +3,7 @@ module github.com/justone/dockviz
 go 1.19
 
 require (
+	github.com/docker/docker v20.10.18+incompatible
 	github.com/fsouza/go-dockerclient v1.8.3
 	github.com/jessevdk/go-flags v1.5.0
 )
@@ -15,7 +16,6 @@ require (
 	github.com/containerd/cgroups v1.0.4 // indirect
 	github.com/containerd/containerd v1.6.8 // indirect
 	github.com/containerd/continuity v0.3.0 // indirect
-	github.com/docker/docker v20.10.18+incompatible // indirect
 	github.com/docker/go-connections v0.4.0 // indirect
 	github.com/docker/go-units v0.5.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
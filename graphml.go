@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// AttributeGraph is a graph whose nodes and edges carry typed attributes,
+// written out as GraphML or GEXF for graph analysis tools.  Values line up
+// with NodeAttrs and EdgeAttrs by index.
+type AttributeGraph struct {
+	NodeAttrs []GraphAttr
+	EdgeAttrs []GraphAttr
+	Nodes     []AttributeNode
+	Edges     []AttributeEdge
+}
+
+type GraphAttr struct {
+	Name string
+	Type string
+}
+
+type AttributeNode struct {
+	Id     string
+	Label  string
+	Values []string
+}
+
+type AttributeEdge struct {
+	Source string
+	Target string
+	Values []string
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	Id   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	Id          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	Id   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type gexfDoc struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	Id    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	Id        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	Id        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func (g *AttributeGraph) AddNode(id string, label string, values ...string) {
+	g.Nodes = append(g.Nodes, AttributeNode{id, label, values})
+}
+
+func (g *AttributeGraph) AddEdge(source string, target string, values ...string) {
+	g.Edges = append(g.Edges, AttributeEdge{source, target, values})
+}
+
+func graphToGraphML(g *AttributeGraph) (string, error) {
+	doc := graphMLDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{Id: "docker", EdgeDefault: "directed"},
+	}
+
+	// GraphML has no built in label, so it is written as a node attribute
+	// named the way yEd and Gephi look for it
+	doc.Keys = append(doc.Keys, graphMLKey{"label", "node", "label", "string"})
+	for i, attr := range g.NodeAttrs {
+		doc.Keys = append(doc.Keys, graphMLKey{"n" + strconv.Itoa(i), "node", attr.Name, attr.Type})
+	}
+	for i, attr := range g.EdgeAttrs {
+		doc.Keys = append(doc.Keys, graphMLKey{"e" + strconv.Itoa(i), "edge", attr.Name, attr.Type})
+	}
+
+	for _, node := range g.Nodes {
+		graphNode := graphMLNode{Id: node.Id, Data: []graphMLData{{"label", node.Label}}}
+		for i, value := range node.Values {
+			graphNode.Data = append(graphNode.Data, graphMLData{"n" + strconv.Itoa(i), value})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphNode)
+	}
+	for _, edge := range g.Edges {
+		graphEdge := graphMLEdge{Source: edge.Source, Target: edge.Target}
+		for i, value := range edge.Values {
+			graphEdge.Data = append(graphEdge.Data, graphMLData{"e" + strconv.Itoa(i), value})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphEdge)
+	}
+
+	return marshalGraphXML(doc, "GraphML")
+}
+
+func graphToGEXF(g *AttributeGraph) (string, error) {
+	doc := gexfDoc{
+		Xmlns:   "http://www.gexf.net/1.2draft",
+		Version: "1.2",
+		Graph:   gexfGraph{DefaultEdgeType: "directed"},
+	}
+
+	nodeAttrs := gexfAttributes{Class: "node"}
+	for i, attr := range g.NodeAttrs {
+		nodeAttrs.Attributes = append(nodeAttrs.Attributes, gexfAttribute{strconv.Itoa(i), attr.Name, attr.Type})
+	}
+	edgeAttrs := gexfAttributes{Class: "edge"}
+	for i, attr := range g.EdgeAttrs {
+		edgeAttrs.Attributes = append(edgeAttrs.Attributes, gexfAttribute{strconv.Itoa(i), attr.Name, attr.Type})
+	}
+	doc.Graph.Attributes = []gexfAttributes{nodeAttrs, edgeAttrs}
+
+	for _, node := range g.Nodes {
+		graphNode := gexfNode{Id: node.Id, Label: node.Label}
+		for i, value := range node.Values {
+			graphNode.AttValues = append(graphNode.AttValues, gexfAttValue{strconv.Itoa(i), value})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphNode)
+	}
+	for index, edge := range g.Edges {
+		graphEdge := gexfEdge{Id: strconv.Itoa(index), Source: edge.Source, Target: edge.Target}
+		for i, value := range edge.Values {
+			graphEdge.AttValues = append(graphEdge.AttValues, gexfAttValue{strconv.Itoa(i), value})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphEdge)
+	}
+
+	return marshalGraphXML(doc, "GEXF")
+}
+
+func marshalGraphXML(doc interface{}, format string) (string, error) {
+	result, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Error writing %s: %s", format, err)
+	}
+
+	return xml.Header + string(result) + "\n", nil
+}
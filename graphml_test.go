@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func Test_GraphML(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+	result, err := graphToGraphML(jsonContainersToAttributeGraph(containers, false))
+	if err != nil {
+		t.Fatalf("graphml failed: %s", err)
+	}
+
+	var doc graphMLDoc
+	if err := xml.Unmarshal([]byte(result), &doc); err != nil {
+		t.Fatalf("graphml content '%s' is not valid xml: %s", result, err)
+	}
+
+	for _, regexp := range compileRegexps(t, []string{
+		`<key id="label" for="node" attr.name="label" attr.type="string"></key>`,
+		`<key id="n5" for="node" attr.name="Created" attr.type="long"></key>`,
+		`<key id="e0" for="edge" attr.name="Alias" attr.type="string"></key>`,
+		`(?s)<node id="redis">\s*<data key="label">redis</data>.*<data key="n6">6379/tcp</data>`,
+		`(?s)<edge source="redis" target="app1">\s*<data key="e0">db</data>`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("graphml content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	// containers without a name are keyed by their ID instead
+	unnamedJSON := `[{"Status":"Up 1 minute","Names":[],"Image":"busybox:latest","Id":"a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","Created":1399985983,"Command":"sh"}]`
+	containers, _ = parseContainersJSON([]byte(unnamedJSON))
+	result, err = graphToGraphML(jsonContainersToAttributeGraph(containers, false))
+	if err != nil {
+		t.Fatalf("graphml failed: %s", err)
+	}
+	for _, regexp := range compileRegexps(t, []string{
+		`<node id="a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6">`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("graphml content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+func Test_GEXF(t *testing.T) {
+	gexfJSON := `[{ "VirtualSize": 662553464, "Size": 0, "RepoTags": [ "foo:latest", "foo:1.0" ], "ParentId": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Id": "c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470", "Created": 1386142123, "CreatedBy": "/bin/sh -c echo \"<&>\"" },{ "VirtualSize": 662553464, "Size": 662553464, "RepoTags": [ "<none>:<none>" ], "ParentId": "", "Id": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Created": 1386114144 }]`
+
+	im, _ := parseImagesJSON([]byte(gexfJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	result, err := graphToGEXF(jsonToAttributeGraph(collectRoots(im), collectChildren(im)))
+	if err != nil {
+		t.Fatalf("gexf failed: %s", err)
+	}
+
+	var doc gexfDoc
+	if err := xml.Unmarshal([]byte(result), &doc); err != nil {
+		t.Fatalf("gexf content '%s' is not valid xml: %s", result, err)
+	}
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+		t.Fatalf("gexf content '%s' has the wrong number of nodes or edges", result)
+	}
+
+	tagged := doc.Graph.Nodes[1]
+	if tagged.Label != "c87be8e5e697" || tagged.AttValues[1].Value != "foo:latest, foo:1.0" || tagged.AttValues[5].Value != `/bin/sh -c echo "<&>"` {
+		t.Fatalf("gexf node '%+v' does not carry the image attributes", tagged)
+	}
+	if doc.Graph.Nodes[0].AttValues[1].Value != "" {
+		t.Fatalf("gexf node '%+v' should not list <none> tags", doc.Graph.Nodes[0])
+	}
+
+	for _, regexp := range compileRegexps(t, []string{
+		`<gexf xmlns="http://www.gexf.net/1.2draft" version="1.2">`,
+		`<attribute id="2" title="Size" type="long"></attribute>`,
+		`<edge id="0" source="4c1208b690c6[0-9a-f]*" target="c87be8e5e697[0-9a-f]*">`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("gexf content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
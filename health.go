@@ -0,0 +1,178 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// ContainerCondition is what inspecting a container tells us about its
+// health and restarts, beyond the Status line of the listing.
+type ContainerCondition struct {
+	Health        string `json:",omitempty"`
+	Restarting    bool   `json:",omitempty"`
+	OOMKilled     bool   `json:",omitempty"`
+	ExitCode      int    `json:",omitempty"`
+	RestartCount  int    `json:",omitempty"`
+	RestartPolicy string `json:",omitempty"`
+}
+
+type ContainerStyle struct {
+	Name  string
+	Fill  string
+	Color string
+}
+
+// styles for containers in each state, in the order they take precedence
+var (
+	oomKilledStyle  = ContainerStyle{"OOM killed", "tomato", "darkred"}
+	unhealthyStyle  = ContainerStyle{"unhealthy", "tomato", "black"}
+	restartingStyle = ContainerStyle{"restarting", "orange", "black"}
+	failedStyle     = ContainerStyle{"exited with an error", "lightgrey", "red"}
+	exitedStyle     = ContainerStyle{"exited", "lightgrey", "black"}
+	runningStyle    = ContainerStyle{"running", "paleturquoise", "black"}
+)
+
+var containerStyles = []ContainerStyle{oomKilledStyle, unhealthyStyle, restartingStyle, failedStyle, exitedStyle, runningStyle}
+
+var exitCodeRegexp = regexp.MustCompile(`^Exited \((-?\d+)\)`)
+
+var healthRegexp = regexp.MustCompile(`\((healthy|unhealthy|health: starting)\)`)
+
+func containerCondition(details *docker.Container) ContainerCondition {
+	condition := ContainerCondition{
+		Health:       details.State.Health.Status,
+		Restarting:   details.State.Restarting,
+		OOMKilled:    details.State.OOMKilled,
+		ExitCode:     details.State.ExitCode,
+		RestartCount: details.RestartCount,
+	}
+	if details.HostConfig != nil {
+		condition.RestartPolicy = details.HostConfig.RestartPolicy.Name
+	}
+	return condition
+}
+
+// containerHealth falls back to the health docker ps puts in the Status,
+// e.g. "Up 5 minutes (unhealthy)", for containers read from stdin.
+func containerHealth(container Container) string {
+	if container.Condition.Health != "" {
+		return container.Condition.Health
+	}
+	if match := healthRegexp.FindStringSubmatch(container.Status); match != nil {
+		return strings.TrimPrefix(match[1], "health: ")
+	}
+	return ""
+}
+
+func containerExitCode(container Container) int {
+	if container.Condition.ExitCode != 0 {
+		return container.Condition.ExitCode
+	}
+	if match := exitCodeRegexp.FindStringSubmatch(container.Status); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code
+	}
+	return 0
+}
+
+func (s ContainerStyle) dotAttributes() string {
+	attributes := fmt.Sprintf("shape=box,fillcolor=\"%s\",style=\"filled,rounded\"", s.Fill)
+	if s.Color != "black" {
+		attributes = attributes + fmt.Sprintf(",color=\"%s\",penwidth=2", s.Color)
+	}
+	if s.Name == oomKilledStyle.Name {
+		attributes = attributes + ",peripheries=2"
+	}
+	return attributes
+}
+
+func containerStyle(container Container) ContainerStyle {
+	state := containerState(container.Status)
+	switch {
+	case container.Condition.OOMKilled:
+		return oomKilledStyle
+	case containerHealth(container) == "unhealthy":
+		return unhealthyStyle
+	case container.Condition.Restarting || state == "restarting":
+		return restartingStyle
+	case state == "exited" && containerExitCode(container) != 0:
+		return failedStyle
+	case state == "exited" || state == "dead":
+		return exitedStyle
+	}
+	return runningStyle
+}
+
+// containerProblems describes everything wrong with a container, or nothing
+// if it is fine.  Restarts are only counted while the container is still
+// restarting or has exited with an error.
+func containerProblems(container Container) []string {
+	var problems []string
+	if container.Condition.OOMKilled {
+		problems = append(problems, "OOM killed")
+	}
+	if containerHealth(container) == "unhealthy" {
+		problems = append(problems, "unhealthy")
+	}
+	restarting := container.Condition.Restarting || containerState(container.Status) == "restarting"
+	if restarting {
+		problems = append(problems, "restarting")
+	}
+	if containerState(container.Status) == "dead" {
+		problems = append(problems, "dead")
+	}
+	code := containerExitCode(container)
+	if code != 0 {
+		problems = append(problems, fmt.Sprintf("exit code %d", code))
+	}
+	// restarts that have since settled down are history, not a problem
+	failing := restarting || (containerState(container.Status) == "exited" && code != 0)
+	if failing && container.Condition.RestartCount > 0 {
+		problems = append(problems, fmt.Sprintf("restart count %d", container.Condition.RestartCount))
+	}
+	return problems
+}
+
+// writeContainerLegend adds a key for the container styles used in a dot
+// graph, if any of them are more than just running or exited.
+func writeContainerLegend(buffer *bytes.Buffer, used map[string]bool) {
+	if !used[oomKilledStyle.Name] && !used[unhealthyStyle.Name] && !used[restartingStyle.Name] && !used[failedStyle.Name] {
+		return
+	}
+
+	buffer.WriteString(" subgraph \"cluster_legend\" {\n")
+	buffer.WriteString("  label=\"Legend\";\n")
+	for _, style := range containerStyles {
+		if used[style.Name] {
+			buffer.WriteString(fmt.Sprintf("  \"legend:%s\" [label=\"%s\",%s];\n", style.Name, style.Name, style.dotAttributes()))
+		}
+	}
+	buffer.WriteString(" }\n")
+}
+
+func jsonContainersToProblems(containers *[]Container, OnlyRunning bool) string {
+	var buffer bytes.Buffer
+
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tIMAGE\tSTATUS\tRESTART POLICY\tPROBLEMS")
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		problems := containerProblems(container)
+		if len(problems) == 0 {
+			continue
+		}
+		policy := container.Condition.RestartPolicy
+		if policy == "" {
+			policy = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", primaryContainerName(container), container.Image, container.Status, policy, strings.Join(problems, ", "))
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
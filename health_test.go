@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const unwellContainersJSON = `[{"Status":"Up 5 minutes (unhealthy)","Names":["/api"],"Image":"shop/api:1.0","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"api"},{"Status":"Restarting (1) 3 seconds ago","Names":["/worker"],"Image":"shop/worker:1.0","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"worker","Condition":{"Restarting":true,"ExitCode":1,"RestartCount":7,"RestartPolicy":"always"}},{"Status":"Exited (137) 1 minute ago","Names":["/db"],"Image":"postgres:15","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399984760,"Command":"postgres","Condition":{"OOMKilled":true,"ExitCode":137,"RestartPolicy":"no"}},{"Status":"Up 1 minute (healthy)","Names":["/web"],"Image":"nginx:latest","Id":"4f3b7d6a8e5c2d1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a09","Created":1399984000,"Command":"nginx"},{"Status":"Exited (2) 1 hour ago","Names":["/migrate"],"Image":"shop/api:1.0","Id":"5a4c8e7b9f6d3e2c1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a","Created":1399983000,"Command":"migrate"},{"Status":"Up 2 hours","Names":["/cache"],"Image":"redis:7","Id":"6b5d9f8c0a7e4f3d2c1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b","Created":1399982000,"Command":"redis-server","Condition":{"RestartCount":3,"RestartPolicy":"always"}}]`
+
+func Test_ContainersHealth(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(unwellContainersJSON))
+
+	result := jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "api" \[label="shop/api:1.0\\napi\\n1c0e4a3d5b2f\\nunhealthy",shape=box,fillcolor="tomato",style="filled,rounded"\];$`,
+		`(?m)^ "worker" \[label="shop/worker:1.0\\nworker\\n2d1f5b4e6c3a\\nrestarting\\nexit code 1\\nrestart count 7",shape=box,fillcolor="orange",`,
+		`(?m)^ "db" \[label="postgres:15\\ndb\\n3e2a6c5f7d4b\\nOOM killed\\nexit code 137",shape=box,fillcolor="tomato",style="filled,rounded",color="darkred",penwidth=2,peripheries=2\];$`,
+		`(?m)^ "web" \[label="nginx:latest\\nweb\\n4f3b7d6a8e5c",shape=box,fillcolor="paleturquoise",style="filled,rounded"\];$`,
+		`(?m)^ "cache" \[label="redis:7\\ncache\\n6b5d9f8c0a7e",shape=box,fillcolor="paleturquoise",style="filled,rounded"\];$`,
+		`(?m)^ "migrate" \[label="shop/api:1.0\\nmigrate\\n5a4c8e7b9f6d\\nexit code 2",shape=box,fillcolor="lightgrey",style="filled,rounded",color="red",penwidth=2\];$`,
+		`(?s) subgraph "cluster_legend" {\n  label="Legend";\n  "legend:OOM killed".*  "legend:running".*\n }\n}\n$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	healthy, _ := parseContainersJSON([]byte(containersJSON))
+	if result := jsonContainersToDot(healthy, false, false); strings.Contains(result, "Legend") {
+		t.Fatalf("containers dot content '%s' should only have a legend when something is wrong", result)
+	}
+
+	result = jsonContainersToProblems(containers, false)
+	expected := "NAME     IMAGE            STATUS                        RESTART POLICY  PROBLEMS\n" +
+		"api      shop/api:1.0     Up 5 minutes (unhealthy)      -               unhealthy\n" +
+		"db       postgres:15      Exited (137) 1 minute ago     no              OOM killed, exit code 137\n" +
+		"migrate  shop/api:1.0     Exited (2) 1 hour ago         -               exit code 2\n" +
+		"worker   shop/worker:1.0  Restarting (1) 3 seconds ago  always          restarting, exit code 1, restart count 7\n"
+	if result != expected {
+		t.Fatalf("containers problems content '%s' did not match '%s'", result, expected)
+	}
+}
@@ -9,7 +9,7 @@ type HelpCommand struct {
 var helpCommand HelpCommand
 
 func (x *HelpCommand) Execute(args []string) error {
-	fmt.Println(`Dockviz: Visualizing Docker Data
+	fmt.Print(`Dockviz: Visualizing Docker Data
 
 Connecting to Docker:
 
@@ -38,9 +38,9 @@ https://github.com/justone/dockviz/issues/12#issuecomment-186682489
 
 Visualizing:
 
-Dockviz can visualize both images and containers. For more information on the
-options each subcommand supports, run them with the '--help' flag (e.g.
-'dockviz images --help').
+Dockviz can visualize images, containers, the processes running in them, and
+swarm services.  For more information on the options each subcommand
+supports, run them with the '--help' flag (e.g. 'dockviz images --help').
 `)
 
 	return nil
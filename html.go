@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+type HTMLReport struct {
+	Generated  string
+	Images     []ImageNode
+	Containers []Container
+	Links      []ContainerLink
+	Shares     []ContainerShare
+	Risks      map[string]string
+}
+
+// jsonToHTML renders the image tree, and the containers grouped by image if
+// there are any, as a single self-contained HTML page.  Everything (data, styles and
+// script) is inlined so the file can be opened offline.
+func jsonToHTML(roots []Image, byParent map[string][]Image, dispOpts DisplayOpts, containers *[]Container) (string, error) {
+	var buffer bytes.Buffer
+
+	report := HTMLReport{
+		Generated: time.Now().Format(time.RFC1123),
+		Images:    imagesToNodes(roots, byParent, dispOpts),
+	}
+	if containers != nil {
+		report.Containers = *containers
+		report.Links = collectContainerLinks(containers, false)
+		report.Shares = collectContainerShares(containers, false)
+		report.Risks = make(map[string]string)
+		for _, container := range *containers {
+			if risk := containerRiskList(container); risk != "" {
+				report.Risks[container.Id] = risk
+			}
+		}
+	}
+
+	if err := htmlTemplate.Execute(&buffer, report); err != nil {
+		return "", fmt.Errorf("Error writing HTML: %s", err)
+	}
+
+	return buffer.String(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>dockviz report</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 1em 2em; }
+h1 { font-size: 20px; }
+h2 { font-size: 16px; margin-top: 2em; }
+#search { width: 30em; padding: 4px; }
+ul.tree { list-style: none; padding-left: 1.2em; margin: 0; }
+ul.tree li { margin: 1px 0; }
+.node { display: inline-block; padding: 1px 6px; border-radius: 4px; cursor: default; }
+.node.tagged { border: 1px solid #5aa; }
+.id { font-family: monospace; }
+.size { color: #555; }
+.tags { font-weight: bold; }
+.match { outline: 2px solid orange; }
+.hidden { display: none; }
+.toggle { display: inline-block; width: 1em; cursor: pointer; color: #777; }
+.node.container { background: paleturquoise; }
+.node.exited { color: #888; background: #eee; }
+.risk { color: #b00; }
+</style>
+</head>
+<body>
+<h1>dockviz report</h1>
+<p>Generated {{.Generated}}.</p>
+<input id="search" type="search" placeholder="Search by tag, id, name or image">
+
+<h2>Images</h2>
+<div id="images"></div>
+
+{{if .Containers}}
+<h2>Containers</h2>
+<div id="containers"></div>
+{{end}}
+
+<script>
+var images = {{.Images}};
+var containers = {{.Containers}};
+var links = {{.Links}};
+var shares = {{.Shares}};
+var risks = {{.Risks}} || {};
+
+function humanSize(raw) {
+	var sizes = ["B", "KB", "MB", "GB", "TB"];
+	var ind = 0;
+	while (raw >= 1000 && ind < sizes.length - 1) {
+		raw = raw / 1000;
+		ind++;
+	}
+	return raw.toFixed(1) + " " + sizes[ind];
+}
+
+function maxSize(nodes) {
+	var max = 0;
+	(nodes || []).forEach(function(node) {
+		max = Math.max(max, node.Size, maxSize(node.Children));
+	});
+	return max;
+}
+
+var largest = maxSize(images) || 1;
+
+function el(tag, cls, text) {
+	var e = document.createElement(tag);
+	if (cls) { e.className = cls; }
+	if (text) { e.textContent = text; }
+	return e;
+}
+
+function addChildren(li, toggle, children) {
+	li.appendChild(children);
+	toggle.onclick = function() {
+		children.classList.toggle("hidden");
+		toggle.textContent = children.classList.contains("hidden") ? "▸" : "▾";
+	};
+}
+
+function renderTree(nodes) {
+	var ul = el("ul", "tree");
+	(nodes || []).forEach(function(node) {
+		var li = el("li");
+		var toggle = el("span", "toggle", node.Children ? "▾" : "");
+		li.appendChild(toggle);
+
+		var label = el("span", "node" + (node.RepoTags ? " tagged" : ""));
+		label.appendChild(el("span", "id", node.OrigId.replace(/^.*:/, "").substring(0, 12)));
+		label.appendChild(document.createTextNode(" "));
+		var size = node.VirtualSize ? node.VirtualSize : node.Size;
+		label.appendChild(el("span", "size", humanSize(size)));
+		if (node.RepoTags) {
+			label.appendChild(document.createTextNode(" "));
+			label.appendChild(el("span", "tags", node.RepoTags.join(", ")));
+		}
+
+		// weight the background by how much this layer adds
+		var weight = node.Size / largest;
+		label.style.background = "rgba(255, 99, 71, " + (0.05 + 0.6 * weight).toFixed(2) + ")";
+
+		label.title = "Id: " + node.OrigId +
+			"\nSize: " + humanSize(node.Size) +
+			(node.VirtualSize ? "\nVirtual Size: " + humanSize(node.VirtualSize) : "") +
+			"\nCreated: " + new Date(node.Created * 1000).toISOString() +
+			(node.CreatedBy ? "\nCreated By: " + node.CreatedBy : "");
+		li.appendChild(label);
+		li.searchText = (node.OrigId + " " + node.Id + " " + (node.RepoTags || []).join(" ")).toLowerCase();
+
+		if (node.Children) {
+			addChildren(li, toggle, renderTree(node.Children));
+		}
+		ul.appendChild(li);
+	});
+	return ul;
+}
+
+document.getElementById("images").appendChild(renderTree(images));
+
+// the containers tree mirrors "containers --tree": each image with the
+// containers started from it, and what each container links to or shares
+function renderContainers(containers) {
+	var images = [];
+	var byImage = {};
+	(containers || []).forEach(function(container) {
+		if (!byImage[container.Image]) {
+			byImage[container.Image] = [];
+			images.push(container.Image);
+		}
+		byImage[container.Image].push(container);
+	});
+	images.sort();
+
+	var ul = el("ul", "tree");
+	images.forEach(function(image) {
+		var li = el("li");
+		var toggle = el("span", "toggle", "▾");
+		li.appendChild(toggle);
+		li.appendChild(el("span", "node tagged", image));
+		li.searchText = image.toLowerCase();
+
+		var sub = el("ul", "tree");
+		byImage[image].forEach(function(container) {
+			var name = container.Id;
+			container.Names.forEach(function(n) {
+				if (n.split("/").length == 2) { name = n.substring(1); }
+			});
+			var uses = (links || []).filter(function(link) { return link.Target == name; })
+				.map(function(link) { return link.Alias + " -> " + link.Source; })
+				.concat((shares || []).filter(function(share) { return share.Target == name; })
+				.map(function(share) { return share.Kind + " -> " + share.Source; }));
+			var size = container.SizeRootFs ? humanSize(container.SizeRw || 0) + " (virtual " + humanSize(container.SizeRootFs) + ")" : "";
+			var risk = risks[container.Id] || "";
+
+			var cli = el("li");
+			var ctoggle = el("span", "toggle", uses.length ? "▾" : "");
+			cli.appendChild(ctoggle);
+			var label = el("span", "node container" + (/^Exit/.test(container.Status) ? " exited" : ""));
+			label.appendChild(document.createTextNode(name + " "));
+			label.appendChild(el("span", "id", container.Id.substring(0, 12)));
+			label.appendChild(document.createTextNode(" " + container.Status));
+			if (size) {
+				label.appendChild(document.createTextNode(" "));
+				label.appendChild(el("span", "size", size));
+			}
+			if (risk) {
+				label.appendChild(document.createTextNode(" "));
+				label.appendChild(el("span", "risk", "Risk: " + risk));
+			}
+			label.title = "Id: " + container.Id +
+				"\nImage: " + container.Image +
+				"\nStatus: " + container.Status +
+				"\nCommand: " + container.Command +
+				"\nCreated: " + new Date(container.Created * 1000).toISOString();
+			cli.appendChild(label);
+			cli.searchText = (name + " " + container.Id + " " + container.Image).toLowerCase();
+
+			if (uses.length) {
+				var children = el("ul", "tree");
+				uses.forEach(function(use) {
+					var uli = el("li");
+					uli.appendChild(el("span", "toggle"));
+					uli.appendChild(el("span", "node", use));
+					uli.searchText = use.toLowerCase();
+					children.appendChild(uli);
+				});
+				addChildren(cli, ctoggle, children);
+			}
+			sub.appendChild(cli);
+		});
+		addChildren(li, toggle, sub);
+		ul.appendChild(li);
+	});
+	return ul;
+}
+
+var containersDiv = document.getElementById("containers");
+if (containersDiv) {
+	containersDiv.appendChild(renderContainers(containers));
+}
+
+function filterTree(ul, query) {
+	var any = false;
+	Array.prototype.forEach.call(ul.children, function(li) {
+		var label = li.querySelector(".node");
+		var matched = query != "" && li.searchText.indexOf(query) >= 0;
+		var sub = li.querySelector("ul");
+		var childMatched = sub ? filterTree(sub, query) : false;
+		label.classList.toggle("match", matched);
+		li.classList.toggle("hidden", query != "" && !matched && !childMatched);
+		if (sub && childMatched) { sub.classList.remove("hidden"); }
+		any = any || matched || childMatched;
+	});
+	return any;
+}
+
+document.getElementById("search").oninput = function() {
+	var query = this.value.toLowerCase();
+	filterTree(document.querySelector("#images > ul"), query);
+	if (containersDiv) {
+		filterTree(document.querySelector("#containers > ul"), query);
+	}
+};
+</script>
+</body>
+</html>
+`))
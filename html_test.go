@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_HTML(t *testing.T) {
+	imagesJSON := `[{ "VirtualSize": 662553464, "Size": 0, "RepoTags": [ "foo:latest" ], "ParentId": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Id": "c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470", "Created": 1386142123, "CreatedBy": "/bin/sh -c echo '</script>'" },{ "VirtualSize": 662553464, "Size": 662553464, "RepoTags": [ "<none>:<none>" ], "ParentId": "", "Id": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Created": 1386114144 }]`
+
+	im, _ := parseImagesJSON([]byte(imagesJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+
+	result, err := jsonToHTML(collectRoots(im), collectChildren(im), DisplayOpts{}, containers)
+	if err != nil {
+		t.Fatalf("html report failed: %s", err)
+	}
+
+	for _, regexp := range compileRegexps(t, []string{
+		`(?s)^<!DOCTYPE html>.*</html>\n$`,
+		`var images = \[{"Id":"4c1208b690c6[0-9a-f]*",.*"Children":\[{"Id":"c87be8e5e697`,
+		`"RepoTags":\["foo:latest"\]`,
+		`var containers = \[{"Id":"878602c44611`,
+		`var links = \[{"Source":"redis","Target":"app1","Alias":"db"}`,
+		`<div id="containers"></div>`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("html report content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	// data embedded in the script must not be able to close it early
+	if strings.Count(result, "</script>") != 1 {
+		t.Fatalf("html report content '%s' embeds an unescaped </script>", result)
+	}
+}
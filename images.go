@@ -12,6 +12,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"text/template"
+	"unicode/utf8"
 )
 
 type Image struct {
@@ -23,17 +25,44 @@ type Image struct {
 	Created     int64
 	OrigId      string
 	CreatedBy   string
+	Labels      map[string]string `json:",omitempty"`
 }
 
 type ImagesCommand struct {
-	Dot           bool `short:"d" long:"dot" description:"Show image information as Graphviz dot. You can add a start image id or name -d/--dot [id/name]"`
-	Tree          bool `short:"t" long:"tree" description:"Show image information as tree. You can add a start image id or name -t/--tree [id/name]"`
-	Short         bool `short:"s" long:"short" description:"Show short summary of images (repo name and list of tags)."`
-	NoTruncate    bool `short:"n" long:"no-trunc" description:"Don't truncate the image IDs (only works with tree mode)."`
-	Incremental   bool `short:"i" long:"incremental" description:"Display image size as incremental rather than cumulative."`
-	OnlyLabelled  bool `short:"l" long:"only-labelled" description:"Print only labelled images/containers."`
-	ShowCreatedBy bool `long:"show-created-by" description:"Show the image 'CreatedBy' to help identify layers."`
-	NoHuman       bool `short:"c" long:"no-human" description:"Don't humanize the sizes."`
+	Dot           bool     `short:"d" long:"dot" description:"Show image information as Graphviz dot. You can add a start image id or name -d/--dot [id/name]"`
+	Tree          bool     `short:"t" long:"tree" description:"Show image information as tree. You can add a start image id or name -t/--tree [id/name]"`
+	Short         bool     `short:"s" long:"short" description:"Show short summary of images (repo name and list of tags)."`
+	Treemap       bool     `long:"treemap" description:"Show image disk usage as a treemap in the terminal (sized by $COLUMNS and $LINES). You can add a start image id or name --treemap [id/name]"`
+	TreemapSVG    bool     `long:"treemap-svg" description:"Show image disk usage as an SVG treemap. You can add a start image id or name --treemap-svg [id/name]"`
+	Folded        bool     `long:"folded" description:"Show layer sizes as folded stacks, for flamegraph tools. You can add a start image id or name --folded [id/name]"`
+	GraphML       bool     `long:"graphml" description:"Show image information as GraphML. You can add a start image id or name --graphml [id/name]"`
+	GEXF          bool     `long:"gexf" description:"Show image information as GEXF. You can add a start image id or name --gexf [id/name]"`
+	CSV           bool     `long:"csv" description:"Show one row per layer as CSV. You can add a start image id or name --csv [id/name]"`
+	TSV           bool     `long:"tsv" description:"Show one row per layer as TSV. You can add a start image id or name --tsv [id/name]"`
+	Columns       string   `long:"columns" value-name:"id,tags,size" description:"Columns for --csv/--tsv: id, parent_id, depth, tags, size, virtual_size, created, children, created_by (default all)."`
+	HTML          bool     `long:"html" description:"Show image (and container) information as a self-contained interactive HTML report. You can add a start image id or name --html [id/name]"`
+	SVG           bool     `long:"svg" description:"Show image information as an SVG picture, without needing Graphviz. You can add a start image id or name --svg [id/name]"`
+	Mermaid       bool     `short:"m" long:"mermaid" description:"Show image information as a Mermaid flowchart. You can add a start image id or name -m/--mermaid [id/name]"`
+	JSON          bool     `short:"j" long:"json" description:"Show image information as nested JSON. You can add a start image id or name -j/--json [id/name]"`
+	NoTruncate    bool     `short:"n" long:"no-trunc" description:"Don't truncate the image IDs (only works with tree mode)."`
+	Incremental   bool     `short:"i" long:"incremental" description:"Display image size as incremental rather than cumulative."`
+	OnlyLabelled  bool     `short:"l" long:"only-labelled" description:"Print only labelled images/containers."`
+	ShowCreatedBy bool     `long:"show-created-by" description:"Show the image 'CreatedBy' to help identify layers."`
+	NoHuman       bool     `short:"c" long:"no-human" description:"Don't humanize the sizes."`
+	Containers    bool     `long:"containers" description:"Show containers as leaves of the image they run (with --dot, --tree and --svg)."`
+	Filter        []string `short:"f" long:"filter" value-name:"KEY=VALUE" description:"Only show images matching a filter (and the layers below them): label=, before=, since=, reference= or dangling=. Can be repeated."`
+	Format        string   `long:"format" value-name:"TEMPLATE" description:"Go template for each --tree or --short line. Tree lines see .ID, .ShortID, .Tags, .Size, .VirtualSize, .Created, .CreatedBy, .Depth and .ChildCount; short lines see .Repository and .Tags."`
+}
+
+type ImageNode struct {
+	Id          string
+	OrigId      string
+	RepoTags    []string `json:",omitempty"`
+	Size        int64
+	VirtualSize int64 `json:",omitempty"`
+	Created     int64
+	CreatedBy   string      `json:",omitempty"`
+	Children    []ImageNode `json:",omitempty"`
 }
 
 type DisplayOpts struct {
@@ -41,23 +70,31 @@ type DisplayOpts struct {
 	Incremental   bool
 	NoHuman       bool
 	ShowCreatedBy bool
+	Format        *template.Template
+	Containers    map[string][]Container
 }
 
 var imagesCommand ImagesCommand
 
 func (x *ImagesCommand) Execute(args []string) error {
 	var images *[]Image
+	var containers *[]Container
+
+	filters, err := parseFilters(imagesCommand.Filter, imageFilterKeys)
+	if err != nil {
+		return err
+	}
 
 	stat, err := os.Stdin.Stat()
 	if err != nil {
-		return fmt.Errorf("error reading stdin stat", err)
+		return fmt.Errorf("error reading stdin stat: %s", err)
 	}
 
 	if globalOptions.Stdin && (stat.Mode()&os.ModeCharDevice) == 0 {
 		// read in stdin
 		stdin, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
-			return fmt.Errorf("error reading all input", err)
+			return fmt.Errorf("error reading all input: %s", err)
 		}
 
 		images, err = parseImagesJSON(stdin)
@@ -76,6 +113,7 @@ func (x *ImagesCommand) Execute(args []string) error {
 				image.Created,
 				image.Id,
 				"",
+				image.Labels,
 			})
 		}
 
@@ -97,41 +135,31 @@ func (x *ImagesCommand) Execute(args []string) error {
 			}
 		}
 
-		if ver[0] == 1 && ver[1] <= 21 {
-			clientImages, err := client.ListImages(docker.ListImagesOptions{All: true})
-			if err != nil {
-				return err
-			}
-
-			var ims []Image
-			for _, image := range clientImages {
-				ims = append(ims, Image{
-					image.ID,
-					image.ParentID,
-					image.RepoTags,
-					image.VirtualSize,
-					image.Size,
-					image.Created,
-					image.ID,
-					"",
-				})
-			}
-
-			images = &ims
-		} else {
-			clientImages, err := client.ListImages(docker.ListImagesOptions{})
-			if err != nil {
-				return err
-			}
+		images, err = listImages(client, ver)
+		if err != nil {
+			return err
+		}
 
-			images, err = synthesizeImagesFromHistory(client, clientImages)
+		// the HTML report shows containers alongside the images
+		if imagesCommand.HTML || imagesCommand.Containers {
+			containers, err = listContainers(client)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
-	if imagesCommand.Tree || imagesCommand.Dot {
+	images, err = filterImageList(images, filters)
+	if err != nil {
+		return err
+	}
+
+	format, err := imagesCommand.lineFormat()
+	if err != nil {
+		return err
+	}
+
+	if imagesCommand.treeModes() {
 		var startImage *Image
 		if len(args) > 0 {
 			startImage, err = findStartImage(args[0], images)
@@ -153,9 +181,16 @@ func (x *ImagesCommand) Execute(args []string) error {
 		// build helper map (image -> children)
 		imagesByParent := collectChildren(images)
 
+		// and (image -> containers), so untagged images running
+		// containers survive the filter
+		var imageContainers map[string][]Container
+		if imagesCommand.Containers && containers != nil {
+			imageContainers = containersByImage(images, containers)
+		}
+
 		// filter images
 		if imagesCommand.OnlyLabelled {
-			*images, imagesByParent = filterImages(images, &imagesByParent)
+			*images, imagesByParent = filterImages(images, &imagesByParent, imageContainers)
 		}
 
 		dispOpts := DisplayOpts{
@@ -163,6 +198,8 @@ func (x *ImagesCommand) Execute(args []string) error {
 			imagesCommand.Incremental,
 			imagesCommand.NoHuman,
 			imagesCommand.ShowCreatedBy,
+			format,
+			imageContainers,
 		}
 		if imagesCommand.Tree {
 			fmt.Print(jsonToTree(roots, imagesByParent, dispOpts))
@@ -170,16 +207,132 @@ func (x *ImagesCommand) Execute(args []string) error {
 		if imagesCommand.Dot {
 			fmt.Print(jsonToDot(roots, imagesByParent, dispOpts))
 		}
+		if imagesCommand.Treemap {
+			width, height := terminalSize()
+			fmt.Print(jsonToTreemap(roots, imagesByParent, width, height))
+		}
+		if imagesCommand.TreemapSVG {
+			fmt.Print(jsonToTreemapSVG(roots, imagesByParent, 1024, 768))
+		}
+		if imagesCommand.Folded {
+			fmt.Print(jsonToFolded(roots, imagesByParent))
+		}
+		if imagesCommand.GraphML {
+			result, err := graphToGraphML(jsonToAttributeGraph(roots, imagesByParent))
+			if err != nil {
+				return err
+			}
+			fmt.Print(result)
+		}
+		if imagesCommand.GEXF {
+			result, err := graphToGEXF(jsonToAttributeGraph(roots, imagesByParent))
+			if err != nil {
+				return err
+			}
+			fmt.Print(result)
+		}
+		if imagesCommand.CSV || imagesCommand.TSV {
+			columns, err := parseColumns(imagesCommand.Columns, imageColumns)
+			if err != nil {
+				return err
+			}
+			comma := ','
+			if imagesCommand.TSV {
+				comma = '\t'
+			}
+			result, err := jsonToTable(roots, imagesByParent, dispOpts, columns, comma)
+			if err != nil {
+				return err
+			}
+			fmt.Print(result)
+		}
+		if imagesCommand.HTML {
+			result, err := jsonToHTML(roots, imagesByParent, dispOpts, containers)
+			if err != nil {
+				return err
+			}
+			fmt.Print(result)
+		}
+		if imagesCommand.SVG {
+			fmt.Print(jsonToSVG(roots, imagesByParent, dispOpts))
+		}
+		if imagesCommand.Mermaid {
+			fmt.Print(jsonToMermaid(roots, imagesByParent, dispOpts))
+		}
+		if imagesCommand.JSON {
+			result, err := jsonToJSON(roots, imagesByParent, dispOpts)
+			if err != nil {
+				return err
+			}
+			fmt.Print(result)
+		}
 
 	} else if imagesCommand.Short {
-		fmt.Printf(jsonToShort(images))
+		fmt.Print(jsonToShort(images, DisplayOpts{Format: format}))
 	} else {
-		return fmt.Errorf("Please specify either --dot, --tree, or --short")
+		return fmt.Errorf("Please specify either --dot, --tree, --treemap, --treemap-svg, --folded, --graphml, --gexf, --csv, --tsv, --html, --svg, --mermaid, --json, or --short")
 	}
 
 	return nil
 }
 
+// listImages lists the image layers from the daemon.  Newer daemons only
+// give the parents of images built locally, so the layers are put together
+// from each image's history instead.
+// treeModes reports whether any mode showing the image tree was asked
+// for, which take precedence over --short.
+func (x *ImagesCommand) treeModes() bool {
+	return x.Tree || x.Dot || x.Treemap || x.TreemapSVG || x.Folded || x.GraphML || x.GEXF || x.CSV || x.TSV || x.HTML || x.SVG || x.Mermaid || x.JSON
+}
+
+// lineFormat parses --format, checking it against the view of whichever
+// mode will use it.
+func (x *ImagesCommand) lineFormat() (*template.Template, error) {
+	if len(x.Format) == 0 {
+		return nil, nil
+	}
+	if x.Tree {
+		return parseFormat(x.Format, sampleImageView)
+	}
+	if x.Short && !x.treeModes() {
+		return parseFormat(x.Format, sampleRepoView)
+	}
+	return nil, fmt.Errorf("--format can only be used with --tree or --short")
+}
+
+func listImages(client *docker.Client, ver []int) (*[]Image, error) {
+	if ver[0] == 1 && ver[1] <= 21 {
+		clientImages, err := client.ListImages(docker.ListImagesOptions{All: true})
+		if err != nil {
+			return nil, err
+		}
+
+		var ims []Image
+		for _, image := range clientImages {
+			ims = append(ims, Image{
+				image.ID,
+				image.ParentID,
+				image.RepoTags,
+				image.VirtualSize,
+				image.Size,
+				image.Created,
+				image.ID,
+				"",
+				image.Labels,
+			})
+		}
+
+		return &ims, nil
+	}
+
+	clientImages, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return synthesizeImagesFromHistory(client, clientImages)
+}
+
 func synthesizeImagesFromHistory(client *docker.Client, images []docker.APIImages) (*[]Image, error) {
 	var newImages []Image
 	newImageRoster := make(map[string]*Image)
@@ -200,6 +353,12 @@ func synthesizeImagesFromHistory(client *docker.Client, images []docker.APIImage
 			newID = fmt.Sprintf("synth:%s", hex.EncodeToString(h.Sum(nil)))
 
 			vSize = vSize + history[i].Size
+			// the labels belong to the image itself, its top layer
+			var labels map[string]string
+			if i == 0 {
+				labels = image.Labels
+			}
+
 			existingImage, ok := newImageRoster[newID]
 			if !ok {
 				newImageRoster[newID] = &Image{
@@ -211,8 +370,12 @@ func synthesizeImagesFromHistory(client *docker.Client, images []docker.APIImage
 					history[i].Created,
 					history[i].ID,
 					history[i].CreatedBy,
+					labels,
 				}
 			} else {
+				if labels != nil {
+					existingImage.Labels = labels
+				}
 				if len(history[i].Tags) > 0 {
 					existingImage.RepoTags = append(existingImage.RepoTags, history[i].Tags...)
 				}
@@ -295,6 +458,149 @@ func jsonToDot(roots []Image, byParent map[string][]Image, dispOpts DisplayOpts)
 	return buffer.String()
 }
 
+func jsonToMermaid(roots []Image, byParent map[string][]Image, dispOpts DisplayOpts) string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("flowchart TD\n")
+	buffer.WriteString(" classDef tagged fill:paleturquoise\n")
+	imagesToMermaid(&buffer, roots, byParent, dispOpts)
+
+	return buffer.String()
+}
+
+func imagesToMermaid(buffer *bytes.Buffer, images []Image, byParent map[string][]Image, dispOpts DisplayOpts) {
+	for _, image := range images {
+		nodeID := mermaidID("i", truncate(stripPrefix(image.Id), 12))
+
+		if image.ParentId != "" {
+			buffer.WriteString(fmt.Sprintf(" %s --> %s\n", mermaidID("i", truncate(stripPrefix(image.ParentId), 12)), nodeID))
+		}
+
+		if image.RepoTags[0] != "<none>:<none>" {
+			buffer.WriteString(fmt.Sprintf(" %s(\"%s\"):::tagged\n", nodeID, mermaidLabel(imageLabelParts(image, dispOpts))))
+		} else {
+			buffer.WriteString(fmt.Sprintf(" %s[\"%s\"]\n", nodeID, mermaidLabel(imageLabelParts(image, dispOpts))))
+		}
+		if subimages, exists := byParent[image.Id]; exists {
+			imagesToMermaid(buffer, subimages, byParent, dispOpts)
+		}
+	}
+}
+
+func jsonToSVG(roots []Image, byParent map[string][]Image, dispOpts DisplayOpts) string {
+	var graph Graph
+
+	imagesToGraph(&graph, roots, byParent, dispOpts)
+
+	return graphToSVG(&graph)
+}
+
+func imagesToGraph(graph *Graph, images []Image, byParent map[string][]Image, dispOpts DisplayOpts) {
+	for _, image := range images {
+		if image.ParentId != "" {
+			graph.AddEdge(GraphEdge{Source: image.ParentId, Target: image.Id})
+		}
+
+		if image.RepoTags[0] != "<none>:<none>" {
+			graph.AddNode(GraphNode{image.Id, imageLabelParts(image, dispOpts), "paleturquoise", true})
+		} else {
+			graph.AddNode(GraphNode{image.Id, imageLabelParts(image, dispOpts), "", false})
+		}
+		for _, container := range dispOpts.Containers[image.Id] {
+			containerName := primaryContainerName(container)
+			graph.AddEdge(GraphEdge{Source: image.Id, Target: "container:" + containerName})
+			graph.AddNode(GraphNode{"container:" + containerName, []string{containerName, truncate(container.Id, 12)}, imageContainerColor(container), false})
+		}
+		if subimages, exists := byParent[image.Id]; exists {
+			imagesToGraph(graph, subimages, byParent, dispOpts)
+		}
+	}
+}
+
+func jsonToAttributeGraph(roots []Image, byParent map[string][]Image) *AttributeGraph {
+	graph := &AttributeGraph{
+		NodeAttrs: []GraphAttr{
+			{"OrigId", "string"},
+			{"RepoTags", "string"},
+			{"Size", "long"},
+			{"VirtualSize", "long"},
+			{"Created", "long"},
+			{"CreatedBy", "string"},
+		},
+	}
+
+	imagesToAttributeGraph(graph, roots, byParent)
+
+	return graph
+}
+
+func imagesToAttributeGraph(graph *AttributeGraph, images []Image, byParent map[string][]Image) {
+	for _, image := range images {
+		var tags string
+		if image.RepoTags[0] != "<none>:<none>" {
+			tags = strings.Join(image.RepoTags, ", ")
+		}
+
+		graph.AddNode(image.Id, truncate(stripPrefix(image.OrigId), 12),
+			image.OrigId,
+			tags,
+			strconv.FormatInt(image.Size, 10),
+			strconv.FormatInt(image.VirtualSize, 10),
+			strconv.FormatInt(image.Created, 10),
+			image.CreatedBy,
+		)
+
+		if image.ParentId != "" {
+			graph.AddEdge(image.ParentId, image.Id)
+		}
+
+		if subimages, exists := byParent[image.Id]; exists {
+			imagesToAttributeGraph(graph, subimages, byParent)
+		}
+	}
+}
+
+func jsonToJSON(roots []Image, byParent map[string][]Image, dispOpts DisplayOpts) (string, error) {
+	nodes := imagesToNodes(roots, byParent, dispOpts)
+
+	result, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Error writing JSON: %s", err)
+	}
+
+	return string(result) + "\n", nil
+}
+
+func imagesToNodes(images []Image, byParent map[string][]Image, dispOpts DisplayOpts) []ImageNode {
+	nodes := []ImageNode{}
+	for _, image := range images {
+		node := ImageNode{
+			Id:        image.Id,
+			OrigId:    image.OrigId,
+			Size:      image.Size,
+			Created:   image.Created,
+			CreatedBy: image.CreatedBy,
+		}
+
+		// incremental mode only reports the size each layer adds
+		if !dispOpts.Incremental {
+			node.VirtualSize = image.VirtualSize
+		}
+
+		if image.RepoTags[0] != "<none>:<none>" {
+			node.RepoTags = image.RepoTags
+		}
+
+		if subimages, exists := byParent[image.Id]; exists {
+			node.Children = imagesToNodes(subimages, byParent, dispOpts)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
 func collectChildren(images *[]Image) map[string][]Image {
 	var imagesByParent = make(map[string][]Image)
 	for _, image := range *images {
@@ -308,6 +614,46 @@ func collectChildren(images *[]Image) map[string][]Image {
 	return imagesByParent
 }
 
+// containersByImage finds the image each container runs, by image ID when
+// the daemon gives one, otherwise by tag or ID prefix.
+func containersByImage(images *[]Image, containers *[]Container) map[string][]Container {
+	byImage := make(map[string][]Container)
+	for _, container := range sortedContainers(containers, false) {
+		reference := withTag(container.Image)
+
+		var found *Image
+	IMAGES:
+		for i, image := range *images {
+			if container.ImageID != "" && (container.ImageID == image.OrigId || container.ImageID == image.Id) {
+				found = &(*images)[i]
+				break
+			}
+			for _, tag := range image.RepoTags {
+				if tag == reference {
+					found = &(*images)[i]
+					break IMAGES
+				}
+			}
+			// containers whose tag has since moved on show the image ID
+			if found == nil && len(stripPrefix(container.Image)) >= 12 && strings.HasPrefix(stripPrefix(image.OrigId), stripPrefix(container.Image)) {
+				found = &(*images)[i]
+			}
+		}
+
+		if found != nil {
+			byImage[found.Id] = append(byImage[found.Id], container)
+		}
+	}
+	return byImage
+}
+
+func imageContainerColor(container Container) string {
+	if strings.Contains(container.Status, "Exited") {
+		return "lightgrey"
+	}
+	return "palegreen"
+}
+
 func collectRoots(images *[]Image) []Image {
 	var roots []Image
 	for _, image := range *images {
@@ -319,13 +665,14 @@ func collectRoots(images *[]Image) []Image {
 	return roots
 }
 
-func filterImages(images *[]Image, byParent *map[string][]Image) (filteredImages []Image, filteredChildren map[string][]Image) {
+func filterImages(images *[]Image, byParent *map[string][]Image, containers map[string][]Container) (filteredImages []Image, filteredChildren map[string][]Image) {
 	for i := 0; i < len(*images); i++ {
 		// image is visible
 		//   1. it has a label
 		//   2. it is root
 		//   3. it is a node
-		var visible bool = (*images)[i].RepoTags[0] != "<none>:<none>" || (*images)[i].ParentId == "" || len((*byParent)[(*images)[i].Id]) > 1
+		//   4. it backs a container
+		var visible bool = (*images)[i].RepoTags[0] != "<none>:<none>" || (*images)[i].ParentId == "" || len((*byParent)[(*images)[i].Id]) > 1 || len(containers[(*images)[i].Id]) > 0
 		if visible {
 			filteredImages = append(filteredImages, (*images)[i])
 		} else {
@@ -355,19 +702,21 @@ func jsonToText(buffer *bytes.Buffer, images []Image, byParent map[string][]Imag
 		for index, image := range images {
 			var nextPrefix string = ""
 			if index+1 == length {
-				PrintTreeNode(buffer, image, dispOpts, prefix+"└─")
+				printTreeNode(buffer, image, byParent, dispOpts, prefix+"└─")
 				nextPrefix = "  "
 			} else {
-				PrintTreeNode(buffer, image, dispOpts, prefix+"├─")
+				printTreeNode(buffer, image, byParent, dispOpts, prefix+"├─")
 				nextPrefix = "│ "
 			}
+			containersToText(buffer, dispOpts.Containers[image.Id], len(byParent[image.Id]) > 0, dispOpts, prefix+nextPrefix)
 			if subimages, exists := byParent[image.Id]; exists {
 				jsonToText(buffer, subimages, byParent, dispOpts, prefix+nextPrefix)
 			}
 		}
 	} else {
 		for _, image := range images {
-			PrintTreeNode(buffer, image, dispOpts, prefix+"└─")
+			printTreeNode(buffer, image, byParent, dispOpts, prefix+"└─")
+			containersToText(buffer, dispOpts.Containers[image.Id], len(byParent[image.Id]) > 0, dispOpts, prefix+"  ")
 			if subimages, exists := byParent[image.Id]; exists {
 				jsonToText(buffer, subimages, byParent, dispOpts, prefix+"  ")
 			}
@@ -375,6 +724,36 @@ func jsonToText(buffer *bytes.Buffer, images []Image, byParent map[string][]Imag
 	}
 }
 
+// containersToText lists the containers running an image, ahead of any
+// images built on top of it.
+func containersToText(buffer *bytes.Buffer, containers []Container, moreFollow bool, dispOpts DisplayOpts, prefix string) {
+	for index, container := range containers {
+		branch := "├─"
+		if index+1 == len(containers) && !moreFollow {
+			branch = "└─"
+		}
+
+		containerID := container.Id
+		if !dispOpts.NoTruncate {
+			containerID = truncate(containerID, 12)
+		}
+		buffer.WriteString(fmt.Sprintf("%s%sContainer: %s %s %s\n", prefix, branch, primaryContainerName(container), containerID, container.Status))
+	}
+}
+
+func printTreeNode(buffer *bytes.Buffer, image Image, byParent map[string][]Image, dispOpts DisplayOpts, prefix string) {
+	if dispOpts.Format == nil {
+		PrintTreeNode(buffer, image, dispOpts, prefix)
+		return
+	}
+
+	// every level of the tree adds two characters to the prefix
+	depth := utf8.RuneCountInString(prefix)/2 - 1
+
+	buffer.WriteString(prefix)
+	executeFormat(buffer, dispOpts.Format, newImageView(image, depth, len(byParent[image.Id])))
+}
+
 func PrintTreeNode(buffer *bytes.Buffer, image Image, dispOpts DisplayOpts, prefix string) {
 	var imageID string
 	if dispOpts.NoTruncate {
@@ -383,13 +762,38 @@ func PrintTreeNode(buffer *bytes.Buffer, image Image, dispOpts DisplayOpts, pref
 		imageID = truncate(stripPrefix(image.OrigId), 12)
 	}
 
+	buffer.WriteString(fmt.Sprintf("%s%s %s", prefix, imageID, sizeLabel(image, dispOpts)))
+	if image.RepoTags[0] != "<none>:<none>" {
+		buffer.WriteString(fmt.Sprintf(" Tags: %s", strings.Join(image.RepoTags, ", ")))
+	}
+	if dispOpts.ShowCreatedBy {
+		buffer.WriteString(fmt.Sprintf(" (%s)", SanitizeCommand(image.CreatedBy, 100)))
+	}
+	buffer.WriteString(fmt.Sprintf("\n"))
+}
+
+// imageLabelParts returns the lines used to label an image node: tagged
+// images show their tags, the rest show their size (and CreatedBy).
+func imageLabelParts(image Image, dispOpts DisplayOpts) []string {
+	labelParts := []string{truncate(stripPrefix(image.OrigId), 12)}
+	if image.RepoTags[0] != "<none>:<none>" {
+		return append(labelParts, image.RepoTags...)
+	}
+
+	if dispOpts.ShowCreatedBy && image.CreatedBy != "" {
+		labelParts = append(labelParts, SanitizeCommand(image.CreatedBy, 30))
+	}
+	return append(labelParts, sizeLabel(image, dispOpts))
+}
+
+func sizeLabel(image Image, dispOpts DisplayOpts) string {
 	var size int64
-	var sizeLabel string
+	var label string
 	if dispOpts.Incremental {
-		sizeLabel = "Size"
+		label = "Size"
 		size = image.Size
 	} else {
-		sizeLabel = "Virtual Size"
+		label = "Virtual Size"
 		size = image.VirtualSize
 	}
 
@@ -400,14 +804,7 @@ func PrintTreeNode(buffer *bytes.Buffer, image Image, dispOpts DisplayOpts, pref
 		sizeStr = humanSize(size)
 	}
 
-	buffer.WriteString(fmt.Sprintf("%s%s %s: %s", prefix, imageID, sizeLabel, sizeStr))
-	if image.RepoTags[0] != "<none>:<none>" {
-		buffer.WriteString(fmt.Sprintf(" Tags: %s", strings.Join(image.RepoTags, ", ")))
-	}
-	if dispOpts.ShowCreatedBy {
-		buffer.WriteString(fmt.Sprintf(" (%s)", SanitizeCommand(image.CreatedBy, 100)))
-	}
-	buffer.WriteString(fmt.Sprintf("\n"))
+	return fmt.Sprintf("%s: %s", label, sizeStr)
 }
 
 func megabytes(bytes int64) float64 {
@@ -456,7 +853,7 @@ func parseImagesJSON(rawJSON []byte) (*[]Image, error) {
 	err := json.Unmarshal(rawJSON, &images)
 
 	if err != nil {
-		return nil, fmt.Errorf("Error reading JSON: ", err)
+		return nil, fmt.Errorf("Error reading JSON: %s", err)
 	}
 
 	return &images, nil
@@ -478,34 +875,61 @@ func imagesToDot(buffer *bytes.Buffer, images []Image, byParent map[string][]Ima
 			if dispOpts.ShowCreatedBy {
 				labelParts = append(labelParts, SanitizeCommand(image.CreatedBy, 30))
 			}
-
-			var size int64
-			var sizeLabel string
-			if dispOpts.Incremental {
-				sizeLabel = "Size"
-				size = image.Size
-			} else {
-				sizeLabel = "Virtual Size"
-				size = image.VirtualSize
-			}
-
-			var sizeStr string
-			if dispOpts.NoHuman {
-				sizeStr = strconv.FormatInt(size, 10)
-			} else {
-				sizeStr = humanSize(size)
-			}
-			labelParts = append(labelParts, fmt.Sprintf("%s: %s", sizeLabel, sizeStr))
+			labelParts = append(labelParts, sizeLabel(image, dispOpts))
 
 			buffer.WriteString(fmt.Sprintf(" \"%s\" [label=\"%s\",area=%f]\n", truncate(image.Id, 12), strings.Join(labelParts, "\n"), megabytes(image.Size)))
 		}
+		for _, container := range dispOpts.Containers[image.Id] {
+			containerName := primaryContainerName(container)
+			buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"container:%s\" [style=dashed]\n", truncate(image.Id, 12), containerName))
+			buffer.WriteString(fmt.Sprintf(" \"container:%s\" [label=\"%s\\n%s\",shape=box,fillcolor=\"%s\",style=\"filled\"];\n", containerName, containerName, truncate(container.Id, 12), imageContainerColor(container)))
+		}
 		if subimages, exists := byParent[image.Id]; exists {
 			imagesToDot(buffer, subimages, byParent, dispOpts)
 		}
 	}
 }
 
-func jsonToShort(images *[]Image) string {
+// jsonToFolded writes one line per layer in the folded stack format used by
+// flamegraph tools ("root;layer;layer size"), weighted by the layer size.
+func jsonToFolded(roots []Image, byParent map[string][]Image) string {
+	var buffer bytes.Buffer
+
+	imagesToFolded(&buffer, roots, byParent, "")
+
+	return buffer.String()
+}
+
+func imagesToFolded(buffer *bytes.Buffer, images []Image, byParent map[string][]Image, stack string) {
+	for _, image := range images {
+		frames := foldedFrame(image)
+		if stack != "" {
+			frames = stack + ";" + frames
+		}
+
+		buffer.WriteString(fmt.Sprintf("%s %d\n", frames, image.Size))
+
+		if subimages, exists := byParent[image.Id]; exists {
+			imagesToFolded(buffer, subimages, byParent, frames)
+		}
+	}
+}
+
+func foldedFrame(image Image) string {
+	var frame string
+	if image.RepoTags[0] != "<none>:<none>" {
+		frame = image.RepoTags[0]
+	} else if image.CreatedBy != "" {
+		frame = SanitizeCommand(image.CreatedBy, 60)
+	} else {
+		frame = truncate(stripPrefix(image.OrigId), 12)
+	}
+
+	// semicolons separate frames, so they can't appear inside one
+	return strings.Replace(frame, ";", ",", -1)
+}
+
+func jsonToShort(images *[]Image, dispOpts DisplayOpts) string {
 	var buffer bytes.Buffer
 
 	var byRepo = make(map[string][]string)
@@ -530,7 +954,11 @@ func jsonToShort(images *[]Image) string {
 	}
 
 	for repo, tags := range byRepo {
-		buffer.WriteString(fmt.Sprintf("%s: %s\n", repo, strings.Join(tags, ", ")))
+		if dispOpts.Format != nil {
+			executeFormat(&buffer, dispOpts.Format, RepoView{repo, tags})
+		} else {
+			buffer.WriteString(fmt.Sprintf("%s: %s\n", repo, strings.Join(tags, ", ")))
+		}
 	}
 
 	return buffer.String()
@@ -558,6 +986,34 @@ func SanitizeCommand(CommandStr string, MaxLength int) string {
 	return truncate(temp, MaxLength)
 }
 
+// mermaidID turns an arbitrary name into a safe Mermaid node id.
+func mermaidID(prefix string, name string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(prefix)
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			buffer.WriteRune(r)
+		} else {
+			buffer.WriteString(fmt.Sprintf("_%x_", r))
+		}
+	}
+	return buffer.String()
+}
+
+// mermaidLabel joins label lines and escapes the characters that Mermaid
+// would otherwise interpret inside a quoted label.
+func mermaidLabel(parts []string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		part = strings.Replace(part, "#", "#35;", -1)
+		part = strings.Replace(part, "\"", "#quot;", -1)
+		part = strings.Replace(part, "<", "#lt;", -1)
+		part = strings.Replace(part, ">", "#gt;", -1)
+		escaped[i] = part
+	}
+	return strings.Join(escaped, "<br/>")
+}
+
 func init() {
 	parser.AddCommand("images",
 		"Visualize docker images.",
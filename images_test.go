@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -57,12 +59,15 @@ func Test_Dot(t *testing.T) {
 
 	for _, dotTest := range dotTests {
 		im, _ := parseImagesJSON([]byte(dotTest.json))
+		for i := range *im {
+			(*im)[i].OrigId = (*im)[i].Id
+		}
 		byParent := collectChildren(im)
 		roots := collectRoots(im)
 
 		// TODO: test start image limiting
 
-		result := jsonToDot(roots, byParent)
+		result := jsonToDot(roots, byParent, DisplayOpts{})
 
 		for _, regexp := range allRegex {
 			if !regexp.MatchString(result) {
@@ -99,9 +104,9 @@ func Test_Tree(t *testing.T) {
 			noTrunc:    false,
 			incr:       true,
 			regexps: []string{
-				`(?m)└─4c1208b690c6 Virtual Size: 662.6 MB`,
-				`(?m)  └─735f5db56261 Virtual Size: 10.0 MB`,
-				`(?m)    └─c87be8e5e697 Virtual Size: 2.0 MB Tags: foo:latest`,
+				`(?m)└─4c1208b690c6 Size: 662.6 MB`,
+				`(?m)  └─735f5db56261 Size: 10.0 MB`,
+				`(?m)    └─c87be8e5e697 Size: 2.0 MB Tags: foo:latest`,
 			},
 		},
 		TreeTest{
@@ -129,6 +134,9 @@ func Test_Tree(t *testing.T) {
 
 	for _, treeTest := range treeTests {
 		im, _ := parseImagesJSON([]byte(treeTest.json))
+		for i := range *im {
+			(*im)[i].OrigId = (*im)[i].Id
+		}
 		byParent := collectChildren(im)
 		var roots []Image
 		if len(treeTest.startImage) > 0 {
@@ -138,7 +146,7 @@ func Test_Tree(t *testing.T) {
 		} else {
 			roots = collectRoots(im)
 		}
-		result := jsonToTree(roots, byParent, treeTest.noTrunc, treeTest.incr)
+		result := jsonToTree(roots, byParent, DisplayOpts{NoTruncate: treeTest.noTrunc, Incremental: treeTest.incr})
 
 		for _, regexp := range compileRegexps(t, treeTest.regexps) {
 			if !regexp.MatchString(result) {
@@ -163,7 +171,7 @@ func Test_Short(t *testing.T) {
 
 	for _, shortTest := range shortTests {
 		im, _ := parseImagesJSON([]byte(shortTest.json))
-		result := jsonToShort(im)
+		result := jsonToShort(im, DisplayOpts{})
 
 		for _, regexp := range compileRegexps(t, shortTest.regexps) {
 			if !regexp.MatchString(result) {
@@ -173,6 +181,183 @@ func Test_Short(t *testing.T) {
 	}
 }
 
+func Test_JSON(t *testing.T) {
+	treeJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["foo:latest"],"ParentId":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Created":1386114144}]`
+
+	im, _ := parseImagesJSON([]byte(treeJSON))
+	byParent := collectChildren(im)
+	roots := collectRoots(im)
+
+	result, err := jsonToJSON(roots, byParent, DisplayOpts{})
+	if err != nil {
+		t.Fatalf("images json failed: %s", err)
+	}
+
+	var nodes []ImageNode
+	if err := json.Unmarshal([]byte(result), &nodes); err != nil {
+		t.Fatalf("images json content '%s' is not valid json: %s", result, err)
+	}
+
+	if len(nodes) != 1 || len(nodes[0].Children) != 1 || len(nodes[0].Children[0].Children) != 1 {
+		t.Fatalf("images json content '%s' does not have the expected shape", result)
+	}
+
+	leaf := nodes[0].Children[0].Children[0]
+	if leaf.VirtualSize != 674553464 || leaf.RepoTags[0] != "foo:latest" {
+		t.Fatalf("images json leaf '%+v' is missing fields", leaf)
+	}
+	if nodes[0].RepoTags != nil {
+		t.Fatalf("images json root '%+v' should not list <none> tags", nodes[0])
+	}
+
+	result, _ = jsonToJSON(roots, byParent, DisplayOpts{Incremental: true})
+	for _, regexp := range compileRegexps(t, []string{`"Size": 10000000`}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("images json content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	if regexp.MustCompile(`VirtualSize`).MatchString(result) {
+		t.Fatalf("images json content '%s' should not include VirtualSize when incremental", result)
+	}
+}
+
+func Test_Mermaid(t *testing.T) {
+	mermaidJSON := `[{ "VirtualSize": 662553464, "Size": 0, "RepoTags": [ "foo:latest" ], "ParentId": "735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470", "Id": "c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470", "Created": 1386142123 },{ "VirtualSize": 662553464, "Size": 0, "RepoTags": [ "<none>:<none>" ], "ParentId": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Id": "735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470", "Created": 1386142123, "CreatedBy": "/bin/sh -c #(nop) CMD [\"<bash>\"]" },{ "VirtualSize": 662553464, "Size": 662553464, "RepoTags": [ "<none>:<none>" ], "ParentId": "", "Id": "sha256:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Created": 1386114144 }]`
+
+	im, _ := parseImagesJSON([]byte(mermaidJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	(*im)[1].ParentId = "sha256:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358"
+	byParent := collectChildren(im)
+	roots := collectRoots(im)
+
+	result := jsonToMermaid(roots, byParent, DisplayOpts{ShowCreatedBy: true})
+
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^flowchart TD$`,
+		`(?m)^ i4c1208b690c6\["4c1208b690c6<br/>Virtual Size: 662.6 MB"\]$`,
+		`(?m)^ i4c1208b690c6 --> i735f5db56261$`,
+		`(?m)^ i735f5db56261\["735f5db56261<br/>CMD \[ #lt;bash#gt; \]<br/>Virtual Size: 662.6 MB"\]$`,
+		`(?m)^ i735f5db56261 --> ic87be8e5e697$`,
+		`(?m)^ ic87be8e5e697\("c87be8e5e697<br/>foo:latest"\):::tagged$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("images mermaid content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+func Test_MermaidEscaping(t *testing.T) {
+	result := mermaidLabel([]string{`say "hi" #1`, "<none>"})
+	expected := "say #quot;hi#quot; #35;1<br/>#lt;none#gt;"
+	if result != expected {
+		t.Fatalf("mermaid label '%s' did not match '%s'", result, expected)
+	}
+
+	if mermaidID("c", "my-app.1") != "cmy_2d_app_2e_1" {
+		t.Fatalf("mermaid id '%s' was not sanitized", mermaidID("c", "my-app.1"))
+	}
+}
+
+func Test_SVG(t *testing.T) {
+	svgJSON := `[{ "VirtualSize": 662553464, "Size": 0, "RepoTags": [ "foo:latest" ], "ParentId": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Id": "c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470", "Created": 1386142123 },{ "VirtualSize": 662553464, "Size": 662553464, "RepoTags": [ "<none>:<none>" ], "ParentId": "", "Id": "4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358", "Created": 1386114144 }]`
+
+	im, _ := parseImagesJSON([]byte(svgJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	result := jsonToSVG(collectRoots(im), collectChildren(im), DisplayOpts{})
+
+	for _, regexp := range compileRegexps(t, []string{
+		`(?s)^<\?xml.*<svg .*</svg>\n$`,
+		`(?s)<g id="4c1208b690c6[0-9a-f]*">\s*<rect [^>]*rx="0" fill="white"[^>]*/>\s*<text [^>]*>4c1208b690c6</text>\s*<text [^>]*>Virtual Size: 662.6 MB</text>`,
+		`(?s)<g id="c87be8e5e697[0-9a-f]*">\s*<rect [^>]*rx="6" fill="paleturquoise"[^>]*/>\s*<text [^>]*>c87be8e5e697</text>\s*<text [^>]*>foo:latest</text>`,
+		`<polyline points="[0-9., ]+" fill="none" stroke="black" marker-end="url\(#arrow\)"/>`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("images svg content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+func Test_Folded(t *testing.T) {
+	foldedJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["foo:latest"],"ParentId":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123,"CreatedBy":"/bin/sh -c apt-get update; apt-get install -y   curl"},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Created":1386114144}]`
+
+	im, _ := parseImagesJSON([]byte(foldedJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	result := jsonToFolded(collectRoots(im), collectChildren(im))
+
+	expected := "4c1208b690c6 662553464\n" +
+		"4c1208b690c6;apt-get update, apt-get install -y curl 10000000\n" +
+		"4c1208b690c6;apt-get update, apt-get install -y curl;foo:latest 2000000\n"
+	if result != expected {
+		t.Fatalf("images folded content '%s' did not match '%s'", result, expected)
+	}
+}
+
+func Test_Format(t *testing.T) {
+	formatJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["foo:latest","foo:1.0"],"ParentId":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Created":1386114144}]`
+
+	im, _ := parseImagesJSON([]byte(formatJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+
+	format, err := parseFormat(`{{.ShortID}} {{.Depth}}/{{.ChildCount}} {{humanSize .Size}} [{{join .Tags ","}}] {{.Created.Unix}}`, sampleImageView)
+	if err != nil {
+		t.Fatalf("valid format was rejected: %s", err)
+	}
+	result := jsonToTree(collectRoots(im), collectChildren(im), DisplayOpts{Format: format})
+
+	expected := "└─4c1208b690c6 0/1 662.6 MB [] 1386114144\n" +
+		"  └─735f5db56261 1/1 10.0 MB [] 1386142123\n" +
+		"    └─c87be8e5e697 2/0 2.0 MB [foo:latest,foo:1.0] 1386142123\n"
+	if result != expected {
+		t.Fatalf("images tree content '%s' did not match '%s'", result, expected)
+	}
+
+	format, _ = parseFormat(`{{.Repository}}={{len .Tags}}`, sampleRepoView)
+	result = jsonToShort(im, DisplayOpts{Format: format})
+	if result != "foo=2\n" {
+		t.Fatalf("images short content '%s' did not match 'foo=2'", result)
+	}
+
+	// indexing and slicing work on real data, so must not be rejected
+	format, err = parseFormat(`{{slice .ID 0 12}} {{index .Tags 0}}`, sampleImageView)
+	if err != nil {
+		t.Fatalf("valid format was rejected: %s", err)
+	}
+	result = jsonToTree(collectRoots(im)[:1], collectChildren(im), DisplayOpts{Format: format})
+	if !strings.HasSuffix(result, "    └─c87be8e5e697 foo:latest\n") {
+		t.Fatalf("images tree content '%s' did not end with the tagged layer", result)
+	}
+	if _, err := parseFormat(`{{index .Tags 0}}`, sampleRepoView); err != nil {
+		t.Fatalf("valid format was rejected: %s", err)
+	}
+
+	if _, err := parseFormat(`{{.Repository}}`, sampleImageView); err == nil {
+		t.Error("format with unknown field did not cause an error")
+	}
+	if _, err := parseFormat(`{{.ID`, sampleImageView); err == nil {
+		t.Error("unparseable format did not cause an error")
+	}
+
+	// tree lines take precedence over --short, and other modes have no
+	// lines to format
+	if _, err := (&ImagesCommand{Tree: true, Short: true, Format: `{{.Repository}}`}).lineFormat(); err == nil {
+		t.Error("short format was accepted for tree lines")
+	}
+	if _, err := (&ImagesCommand{Tree: true, Short: true, Format: `{{.ShortID}}`}).lineFormat(); err != nil {
+		t.Errorf("tree format was rejected alongside --short: %s", err)
+	}
+	if _, err := (&ImagesCommand{Dot: true, Short: true, Format: `{{.Repository}}`}).lineFormat(); err == nil {
+		t.Error("format was accepted for dot output")
+	}
+}
+
 func compileRegexps(t *testing.T, regexpStrings []string) []*regexp.Regexp {
 
 	compiledRegexps := []*regexp.Regexp{}
@@ -186,3 +371,43 @@ func compileRegexps(t *testing.T, regexpStrings []string) []*regexp.Regexp {
 
 	return compiledRegexps
 }
+
+func Test_ImageContainers(t *testing.T) {
+	imagesJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["foo:latest"],"ParentId":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142125},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Created":1386114144}]`
+	containersJSON := `[{"Status":"Up 2 minutes","Names":["/web"],"Image":"foo","Id":"6a2fa6a3c2d43738a1b850a17b3da212970efce83d119da2707177d1e506567f","Created":1399985012,"Command":"/bin/bash"},{"Status":"Exited (0) 9 seconds ago","Names":["/old"],"Image":"735f5db56261","ImageID":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"878602c44611115d52118edeb768fc62de8cfed8c3bdb8c5cd2e149cb1c20afa","Created":1399985983,"Command":"/bin/bash"},{"Status":"Up 1 minute","Names":["/gone"],"Image":"bar:1.0","Id":"5d7e818a4ea3cf01bdf5e1fdaebf645e11469ba0e55a506cde31834732766421","Created":1399984760,"Command":"/bin/bash"}]`
+
+	im, _ := parseImagesJSON([]byte(imagesJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+	byImage := containersByImage(im, containers)
+
+	result := jsonToTree(collectRoots(im), collectChildren(im), DisplayOpts{Containers: byImage})
+	expected := "└─4c1208b690c6 Virtual Size: 662.6 MB\n" +
+		"  └─735f5db56261 Virtual Size: 672.6 MB\n" +
+		"    ├─Container: old 878602c44611 Exited (0) 9 seconds ago\n" +
+		"    └─c87be8e5e697 Virtual Size: 674.6 MB Tags: foo:latest\n" +
+		"      └─Container: web 6a2fa6a3c2d4 Up 2 minutes\n"
+	if result != expected {
+		t.Fatalf("images tree content '%s' did not match '%s'", result, expected)
+	}
+
+	result = jsonToDot(collectRoots(im), collectChildren(im), DisplayOpts{Containers: byImage})
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "c87be8e5e697" -> "container:web" \[style=dashed\]$`,
+		`(?m)^ "container:web" \[label="web\\n6a2fa6a3c2d4",shape=box,fillcolor="palegreen",style="filled"\];$`,
+		`(?m)^ "735f5db56261" -> "container:old" \[style=dashed\]$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("images dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	// the untagged layer running a container survives --only-labelled
+	byParent := collectChildren(im)
+	filtered, _ := filterImages(im, &byParent, byImage)
+	if len(filtered) != 3 {
+		t.Fatalf("filtered images '%v' should keep the layer backing a container", filtered)
+	}
+}
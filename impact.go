@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+type ImpactCommand struct {
+	Dot         bool `short:"d" long:"dot" description:"Show all containers as Graphviz dot, with the affected ones highlighted."`
+	OnlyRunning bool `short:"r" long:"running" description:"Only consider running containers, not Exited"`
+}
+
+var impactCommand ImpactCommand
+
+func (x *ImpactCommand) Execute(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Please specify one container name, e.g. 'dockviz impact db'")
+	}
+
+	var containers *[]Container
+
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading stdin stat: %s", err)
+	}
+
+	if globalOptions.Stdin && (stat.Mode()&os.ModeCharDevice) == 0 {
+		// read in stdin
+		stdin, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("error reading all input: %s", err)
+		}
+
+		containers, err = parseContainersJSON(stdin)
+		if err != nil {
+			return err
+		}
+	} else {
+
+		client, err := connect()
+		if err != nil {
+			return err
+		}
+
+		containers, err = listContainers(client)
+		if err != nil {
+			return fmt.Errorf("Unable to connect: %s\nFor help, run 'dockviz help'", err)
+		}
+	}
+
+	var shown []Container
+	for _, container := range *containers {
+		if !(impactCommand.OnlyRunning && strings.HasPrefix(container.Status, "Exit")) {
+			shown = append(shown, container)
+		}
+	}
+	name := resolveContainer(shown, args[0])
+	if name == "" {
+		return fmt.Errorf("No container named '%s'", args[0])
+	}
+
+	if impactCommand.Dot {
+		fmt.Print(jsonContainersToImpactDot(containers, impactCommand.OnlyRunning, name))
+	} else {
+		fmt.Print(jsonContainersToImpactTree(containers, impactCommand.OnlyRunning, name))
+	}
+
+	return nil
+}
+
+// collectImpactDependencies adds containers sharing a named volume to the
+// dependencies used for ordering.  Either side can break the other, so
+// these go both ways.
+func collectImpactDependencies(containers *[]Container, OnlyRunning bool) []ContainerDependency {
+	dependencies := collectContainerDependencies(containers, OnlyRunning)
+
+	_, uses := collectContainerMounts(containers, OnlyRunning)
+	byVolume := make(map[string][]MountUse)
+	var volumes []string
+	for _, use := range uses {
+		if use.Type != "volume" {
+			continue
+		}
+		if _, exists := byVolume[use.Source]; !exists {
+			volumes = append(volumes, use.Source)
+		}
+		byVolume[use.Source] = append(byVolume[use.Source], use)
+	}
+
+	for _, volume := range volumes {
+		for _, a := range byVolume[volume] {
+			for _, b := range byVolume[volume] {
+				if a.Container != b.Container {
+					dependencies = append(dependencies, ContainerDependency{a.Container, b.Container, "volume " + a.SourceLabel})
+				}
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// ImpactStep is a container affected by stopping another, reached through
+// the container it depends on and the reason it does.
+type ImpactStep struct {
+	Container string
+	Via       string
+	Kind      string
+}
+
+// collectImpact walks out from the named container to every container that
+// depends on it, directly or not, nearest first.  Each container is reached
+// once, through the first dependency found.
+func collectImpact(dependencies []ContainerDependency, name string) []ImpactStep {
+	dependents := make(map[string][]ContainerDependency)
+	for _, dependency := range dependencies {
+		dependents[dependency.DependsOn] = append(dependents[dependency.DependsOn], dependency)
+	}
+	for _, list := range dependents {
+		sort.SliceStable(list, func(a, b int) bool {
+			return list[a].Container < list[b].Container
+		})
+	}
+
+	var steps []ImpactStep
+	reached := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependency := range dependents[current] {
+			if reached[dependency.Container] {
+				continue
+			}
+			reached[dependency.Container] = true
+			steps = append(steps, ImpactStep{dependency.Container, current, dependency.Kind})
+			queue = append(queue, dependency.Container)
+		}
+	}
+
+	return steps
+}
+
+// jsonContainersToImpactTree shows the containers affected by stopping the
+// named one, each under the container it depends on.
+func jsonContainersToImpactTree(containers *[]Container, OnlyRunning bool, name string) string {
+	var buffer bytes.Buffer
+
+	steps := collectImpact(collectImpactDependencies(containers, OnlyRunning), name)
+
+	byVia := make(map[string][]ImpactStep)
+	for _, step := range steps {
+		byVia[step.Via] = append(byVia[step.Via], step)
+	}
+	var build func(container string) []TextNode
+	build = func(container string) []TextNode {
+		var nodes []TextNode
+		for _, step := range byVia[container] {
+			nodes = append(nodes, TextNode{Line: fmt.Sprintf("%s (%s)", step.Container, step.Kind), Children: build(step.Container)})
+		}
+		return nodes
+	}
+
+	buffer.WriteString(name + "\n")
+	writeTextTree(&buffer, build(name), "")
+
+	switch len(steps) {
+	case 0:
+		buffer.WriteString("No other containers are affected.\n")
+	case 1:
+		buffer.WriteString("1 other container is affected.\n")
+	default:
+		buffer.WriteString(fmt.Sprintf("%d other containers are affected.\n", len(steps)))
+	}
+
+	return buffer.String()
+}
+
+// jsonContainersToImpactDot draws every container and what it depends on,
+// with the named container in red, the ones it would take down with it in
+// orange and the dependencies between them in red.
+func jsonContainersToImpactDot(containers *[]Container, OnlyRunning bool, name string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph docker {\n")
+
+	dependencies := collectImpactDependencies(containers, OnlyRunning)
+	affected := make(map[string]bool)
+	for _, step := range collectImpact(dependencies, name) {
+		affected[step.Container] = true
+	}
+
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		containerName := primaryContainerName(container)
+		attributes := "fillcolor=\"lightgrey\",style=\"filled\",fontcolor=\"dimgrey\",color=\"grey\""
+		if containerName == name {
+			attributes = "fillcolor=\"tomato\",style=\"filled\",penwidth=2"
+		} else if affected[containerName] {
+			attributes = "fillcolor=\"orange\",style=\"filled\""
+		}
+		buffer.WriteString(fmt.Sprintf(" \"%s\" [label=\"%s\",shape=box,%s];\n", containerName, strings.Join(containerLabelParts(container), "\\n"), attributes))
+	}
+
+	for _, dependency := range dependencies {
+		highlighted := affected[dependency.Container] && (affected[dependency.DependsOn] || dependency.DependsOn == name)
+		attributes := ""
+		if strings.HasPrefix(dependency.Kind, "volume ") {
+			// shared volumes go both ways, so they are drawn once per pair
+			if dependency.DependsOn > dependency.Container {
+				continue
+			}
+			highlighted = (affected[dependency.Container] || dependency.Container == name) && (affected[dependency.DependsOn] || dependency.DependsOn == name)
+			attributes = ",dir=none"
+		}
+
+		if highlighted {
+			attributes = "color=\"red\",fontcolor=\"red\",penwidth=2" + attributes
+		} else {
+			attributes = "color=\"grey\",fontcolor=\"grey\"" + attributes
+		}
+		buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"%s\" [label = \" %s\",%s ]\n", dependency.DependsOn, dependency.Container, dependency.Kind, attributes))
+	}
+
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+func init() {
+	parser.AddCommand("impact",
+		"Show which containers are affected by stopping one.",
+		"",
+		&impactCommand)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ContainersImpact(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(dependentContainersJSON))
+	for i, container := range *containers {
+		if primaryContainerName(container) == "shop-db-1" {
+			(*containers)[i].Mounts = []ContainerMount{{"volume", "pgdata", "/var/lib/docker/volumes/pgdata/_data", "/var/lib/postgresql/data", true}}
+		}
+	}
+	*containers = append(*containers, Container{
+		Id:     "8d7f1b0e2c9a6b5f4e3d2c1b0a9f8e7d6c5b4a392817161514130f0e0d0c0b0a",
+		Image:  "shop/backup:1.0",
+		Names:  []string{"/backup"},
+		Status: "Up 1 minute",
+		Mounts: []ContainerMount{{"volume", "pgdata", "/var/lib/docker/volumes/pgdata/_data", "/data", false}},
+	})
+
+	result := jsonContainersToImpactTree(containers, false, "shop-db-1")
+	expected := "shop-db-1\n" +
+		"├─backup (volume pgdata)\n" +
+		"└─shop-api-1 (depends_on)\n" +
+		"  └─shop-web-1 (depends_on)\n" +
+		"    └─proxy (network)\n" +
+		"4 other containers are affected.\n"
+	if result != expected {
+		t.Fatalf("containers impact '%s' did not match '%s'", result, expected)
+	}
+
+	result = jsonContainersToImpactTree(containers, false, "grafana")
+	if !strings.HasSuffix(result, "No other containers are affected.\n") {
+		t.Fatalf("containers impact '%s' should not have affected anything", result)
+	}
+
+	result = jsonContainersToImpactDot(containers, false, "shop-db-1")
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "shop-db-1" \[label="postgres:15\\nshop-db-1\\n.*",shape=box,fillcolor="tomato",`,
+		`(?m)^ "proxy" \[label=".*",shape=box,fillcolor="orange",`,
+		`(?m)^ "metrics" \[label=".*",shape=box,fillcolor="lightgrey",`,
+		`(?m)^ "shop-web-1" -> "proxy" \[label = " network",color="red",`,
+		`(?m)^ "shop-db-1" -> "shop-api-1" \[label = " depends_on",color="red",`,
+		`(?m)^ "shop-cache-1" -> "shop-api-1" \[label = " depends_on",color="grey",`,
+		`(?m)^ "metrics" -> "grafana" \[label = " link",color="grey",`,
+		`(?m)^ "backup" -> "shop-db-1" \[label = " volume pgdata",color="red",fontcolor="red",penwidth=2,dir=none \]$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers impact dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	if strings.Contains(result, `"shop-db-1" -> "backup"`) {
+		t.Fatalf("containers impact dot content '%s' should draw the shared volume once", result)
+	}
+}
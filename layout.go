@@ -0,0 +1,431 @@
+package main
+
+import (
+	"sort"
+)
+
+// Graph is a renderer-neutral description of the same nodes and edges that
+// the dot output draws.  It is laid out by layoutGraph so that dockviz can
+// produce pictures without an external Graphviz binary.
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+type GraphNode struct {
+	Id      string
+	Label   []string
+	Fill    string
+	Rounded bool
+}
+
+type GraphEdge struct {
+	Source string
+	Target string
+	Label  string
+}
+
+// LayoutNode is a positioned node, with X and Y at its center.
+type LayoutNode struct {
+	Node   GraphNode
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// LayoutEdge is a positioned edge, drawn as a polyline through Points.
+type LayoutEdge struct {
+	Edge   GraphEdge
+	Points [][2]float64
+}
+
+type Layout struct {
+	Width  float64
+	Height float64
+	Nodes  []LayoutNode
+	Edges  []LayoutEdge
+}
+
+const (
+	layoutCharWidth  = 7.0
+	layoutLineHeight = 16.0
+	layoutPadding    = 8.0
+	layoutNodeSep    = 20.0
+	layoutRankSep    = 50.0
+	layoutMargin     = 20.0
+	layoutDummyWidth = 10.0
+	layoutSweeps     = 12
+)
+
+func (g *Graph) AddNode(node GraphNode) {
+	g.Nodes = append(g.Nodes, node)
+}
+
+func (g *Graph) AddEdge(edge GraphEdge) {
+	g.Edges = append(g.Edges, edge)
+}
+
+// layoutGraph places the graph using a layered (Sugiyama-style) layout:
+// cycles are broken, nodes are ranked by longest path, long edges are split
+// with dummy nodes, crossings are reduced with barycenter sweeps and then
+// each rank is spread out horizontally.
+func layoutGraph(g *Graph) *Layout {
+
+	// vertices are the real nodes followed by any dummy nodes
+	var widths, heights []float64
+	index := make(map[string]int)
+	for i, node := range g.Nodes {
+		index[node.Id] = i
+		widths = append(widths, nodeWidth(node))
+		heights = append(heights, nodeHeight(node))
+	}
+	realCount := len(g.Nodes)
+
+	// keep only edges between known nodes, dropping self loops
+	type edgeRef struct {
+		source   int
+		target   int
+		edge     GraphEdge
+		reversed bool
+	}
+	var edges []edgeRef
+	for _, edge := range g.Edges {
+		source, sourceOk := index[edge.Source]
+		target, targetOk := index[edge.Target]
+		if sourceOk && targetOk && source != target {
+			edges = append(edges, edgeRef{source, target, edge, false})
+		}
+	}
+
+	// break cycles by reversing edges that point back up the DFS stack
+	outgoing := make([][]int, realCount)
+	for i, edge := range edges {
+		outgoing[edge.source] = append(outgoing[edge.source], i)
+	}
+	state := make([]int, realCount)
+	var visit func(int)
+	visit = func(v int) {
+		state[v] = 1
+		for _, i := range outgoing[v] {
+			target := edges[i].target
+			if state[target] == 1 {
+				edges[i].reversed = true
+			} else if state[target] == 0 {
+				visit(target)
+			}
+		}
+		state[v] = 2
+	}
+	for v := 0; v < realCount; v++ {
+		if state[v] == 0 {
+			visit(v)
+		}
+	}
+	for i := range edges {
+		if edges[i].reversed {
+			edges[i].source, edges[i].target = edges[i].target, edges[i].source
+		}
+	}
+
+	// rank each node by the longest path from a source
+	inDegree := make([]int, realCount)
+	succs := make([][]int, realCount)
+	for _, edge := range edges {
+		succs[edge.source] = append(succs[edge.source], edge.target)
+		inDegree[edge.target]++
+	}
+	rank := make([]int, realCount)
+	var queue []int
+	for v := 0; v < realCount; v++ {
+		if inDegree[v] == 0 {
+			queue = append(queue, v)
+		}
+	}
+	var topo []int
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		topo = append(topo, v)
+		for _, w := range succs[v] {
+			if rank[v]+1 > rank[w] {
+				rank[w] = rank[v] + 1
+			}
+			inDegree[w]--
+			if inDegree[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+
+	// split edges that span several ranks into chains of dummy vertices
+	chains := make([][]int, len(edges))
+	for i, edge := range edges {
+		chain := []int{edge.source}
+		for r := rank[edge.source] + 1; r < rank[edge.target]; r++ {
+			rank = append(rank, r)
+			widths = append(widths, layoutDummyWidth)
+			heights = append(heights, 0)
+			chain = append(chain, len(rank)-1)
+		}
+		chains[i] = append(chain, edge.target)
+	}
+	vertexCount := len(rank)
+
+	preds := make([][]int, vertexCount)
+	nexts := make([][]int, vertexCount)
+	for _, chain := range chains {
+		for j := 1; j < len(chain); j++ {
+			preds[chain[j]] = append(preds[chain[j]], chain[j-1])
+			nexts[chain[j-1]] = append(nexts[chain[j-1]], chain[j])
+		}
+	}
+
+	// initial order follows a depth first walk, which keeps subtrees together
+	maxRank := 0
+	for _, r := range rank {
+		if r > maxRank {
+			maxRank = r
+		}
+	}
+	layers := make([][]int, maxRank+1)
+	placed := make([]bool, vertexCount)
+	var place func(int)
+	place = func(v int) {
+		if placed[v] {
+			return
+		}
+		placed[v] = true
+		layers[rank[v]] = append(layers[rank[v]], v)
+		for _, w := range nexts[v] {
+			place(w)
+		}
+	}
+	for _, v := range topo {
+		if len(preds[v]) == 0 {
+			place(v)
+		}
+	}
+	for v := 0; v < vertexCount; v++ {
+		place(v)
+	}
+
+	// reduce crossings with alternating barycenter sweeps, keeping the best
+	position := make([]int, vertexCount)
+	updatePositions := func() {
+		for _, layer := range layers {
+			for i, v := range layer {
+				position[v] = i
+			}
+		}
+	}
+	updatePositions()
+	best := copyLayers(layers)
+	bestCrossings := countCrossings(layers, nexts, position)
+	for sweep := 0; sweep < layoutSweeps && bestCrossings > 0; sweep++ {
+		if sweep%2 == 0 {
+			for r := 1; r <= maxRank; r++ {
+				orderByBarycenter(layers[r], preds, position)
+				updatePositions()
+			}
+		} else {
+			for r := maxRank - 1; r >= 0; r-- {
+				orderByBarycenter(layers[r], nexts, position)
+				updatePositions()
+			}
+		}
+		if crossings := countCrossings(layers, nexts, position); crossings < bestCrossings {
+			best = copyLayers(layers)
+			bestCrossings = crossings
+		}
+	}
+	layers = best
+	updatePositions()
+
+	// assign x coordinates, pulling each node towards its neighbours
+	x := make([]float64, vertexCount)
+	for _, layer := range layers {
+		packLayer(layer, widths, x, nil)
+	}
+	for pass := 0; pass < 4; pass++ {
+		for r := 1; r <= maxRank; r++ {
+			packLayer(layers[r], widths, x, desiredX(layers[r], preds, x))
+		}
+		for r := maxRank - 1; r >= 0; r-- {
+			packLayer(layers[r], widths, x, desiredX(layers[r], nexts, x))
+		}
+	}
+
+	// assign y coordinates, one band per rank
+	y := make([]float64, vertexCount)
+	top := layoutMargin
+	for _, layer := range layers {
+		bandHeight := 0.0
+		for _, v := range layer {
+			if heights[v] > bandHeight {
+				bandHeight = heights[v]
+			}
+		}
+		for _, v := range layer {
+			y[v] = top + bandHeight/2
+		}
+		top = top + bandHeight + layoutRankSep
+	}
+
+	// shift everything right so the leftmost node sits on the margin
+	left, right := 0.0, 0.0
+	for v := 0; v < vertexCount; v++ {
+		if v == 0 || x[v]-widths[v]/2 < left {
+			left = x[v] - widths[v]/2
+		}
+		if v == 0 || x[v]+widths[v]/2 > right {
+			right = x[v] + widths[v]/2
+		}
+	}
+
+	layout := &Layout{
+		Width:  right - left + 2*layoutMargin,
+		Height: top - layoutRankSep + layoutMargin,
+	}
+	if vertexCount == 0 {
+		layout.Width = 2 * layoutMargin
+		layout.Height = 2 * layoutMargin
+	}
+	for v := 0; v < vertexCount; v++ {
+		x[v] = x[v] - left + layoutMargin
+	}
+
+	for i, node := range g.Nodes {
+		layout.Nodes = append(layout.Nodes, LayoutNode{node, x[i], y[i], widths[i], heights[i]})
+	}
+	for i, edge := range edges {
+		var points [][2]float64
+		for j, v := range chains[i] {
+			switch j {
+			case 0:
+				points = append(points, [2]float64{x[v], y[v] + heights[v]/2})
+			case len(chains[i]) - 1:
+				points = append(points, [2]float64{x[v], y[v] - heights[v]/2})
+			default:
+				points = append(points, [2]float64{x[v], y[v]})
+			}
+		}
+		if edge.reversed {
+			for a, b := 0, len(points)-1; a < b; a, b = a+1, b-1 {
+				points[a], points[b] = points[b], points[a]
+			}
+		}
+		layout.Edges = append(layout.Edges, LayoutEdge{edge.edge, points})
+	}
+
+	return layout
+}
+
+func nodeWidth(node GraphNode) float64 {
+	longest := 0
+	for _, line := range node.Label {
+		if len([]rune(line)) > longest {
+			longest = len([]rune(line))
+		}
+	}
+	return float64(longest)*layoutCharWidth + 2*layoutPadding
+}
+
+func nodeHeight(node GraphNode) float64 {
+	return float64(len(node.Label))*layoutLineHeight + 2*layoutPadding
+}
+
+func copyLayers(layers [][]int) [][]int {
+	result := make([][]int, len(layers))
+	for i, layer := range layers {
+		result[i] = append([]int{}, layer...)
+	}
+	return result
+}
+
+func orderByBarycenter(layer []int, neighbours [][]int, position []int) {
+	barycenter := make(map[int]float64)
+	for i, v := range layer {
+		if len(neighbours[v]) == 0 {
+			// nodes without neighbours keep their place
+			barycenter[v] = float64(i)
+			continue
+		}
+		sum := 0.0
+		for _, w := range neighbours[v] {
+			sum = sum + float64(position[w])
+		}
+		barycenter[v] = sum / float64(len(neighbours[v]))
+	}
+	sort.SliceStable(layer, func(a, b int) bool {
+		return barycenter[layer[a]] < barycenter[layer[b]]
+	})
+}
+
+func countCrossings(layers [][]int, nexts [][]int, position []int) int {
+	crossings := 0
+	for _, layer := range layers {
+		var segments [][2]int
+		for _, v := range layer {
+			for _, w := range nexts[v] {
+				segments = append(segments, [2]int{position[v], position[w]})
+			}
+		}
+		for a := 0; a < len(segments); a++ {
+			for b := a + 1; b < len(segments); b++ {
+				if (segments[a][0]-segments[b][0])*(segments[a][1]-segments[b][1]) < 0 {
+					crossings++
+				}
+			}
+		}
+	}
+	return crossings
+}
+
+func desiredX(layer []int, neighbours [][]int, x []float64) []float64 {
+	desired := make([]float64, len(layer))
+	for i, v := range layer {
+		desired[i] = x[v]
+		if len(neighbours[v]) > 0 {
+			sum := 0.0
+			for _, w := range neighbours[v] {
+				sum = sum + x[w]
+			}
+			desired[i] = sum / float64(len(neighbours[v]))
+		}
+	}
+	return desired
+}
+
+// packLayer places the layer left to right without overlaps, as close to
+// the desired positions as the minimum separation allows.  With no desired
+// positions the nodes are simply packed from zero.
+func packLayer(layer []int, widths []float64, x []float64, desired []float64) {
+	for i, v := range layer {
+		var want float64
+		if desired != nil {
+			want = desired[i]
+		}
+		if i > 0 {
+			prev := layer[i-1]
+			minX := x[prev] + (widths[prev]+widths[v])/2 + layoutNodeSep
+			if desired == nil || want < minX {
+				want = minX
+			}
+		}
+		x[v] = want
+	}
+
+	// pushing nodes right drifts the layer, so shift it back by the
+	// average distance from where the nodes wanted to be
+	if desired != nil && len(layer) > 0 {
+		drift := 0.0
+		for i, v := range layer {
+			drift = drift + x[v] - desired[i]
+		}
+		drift = drift / float64(len(layer))
+		for _, v := range layer {
+			x[v] = x[v] - drift
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_LayoutGraph(t *testing.T) {
+	var graph Graph
+	for _, id := range []string{"root", "left", "right", "leaf", "far"} {
+		graph.AddNode(GraphNode{Id: id, Label: []string{id}})
+	}
+	graph.AddEdge(GraphEdge{Source: "root", Target: "left"})
+	graph.AddEdge(GraphEdge{Source: "root", Target: "right"})
+	graph.AddEdge(GraphEdge{Source: "left", Target: "leaf"})
+	graph.AddEdge(GraphEdge{Source: "root", Target: "leaf"})
+	graph.AddEdge(GraphEdge{Source: "leaf", Target: "root"})
+	graph.AddEdge(GraphEdge{Source: "far", Target: "missing"})
+
+	layout := layoutGraph(&graph)
+
+	nodes := make(map[string]LayoutNode)
+	for _, node := range layout.Nodes {
+		nodes[node.Node.Id] = node
+		if node.X-node.Width/2 < 0 || node.X+node.Width/2 > layout.Width || node.Y+node.Height/2 > layout.Height {
+			t.Fatalf("node %s at (%f, %f) is outside the %fx%f layout", node.Node.Id, node.X, node.Y, layout.Width, layout.Height)
+		}
+	}
+
+	if !(nodes["root"].Y < nodes["left"].Y && nodes["left"].Y < nodes["leaf"].Y) {
+		t.Fatalf("nodes are not ranked top to bottom: %+v", nodes)
+	}
+	if nodes["left"].Y != nodes["right"].Y {
+		t.Fatalf("siblings are not on the same rank: %+v", nodes)
+	}
+
+	for _, a := range layout.Nodes {
+		for _, b := range layout.Nodes {
+			if a.Node.Id != b.Node.Id && a.Y == b.Y && a.X < b.X && a.X+a.Width/2 > b.X-b.Width/2 {
+				t.Fatalf("nodes %s and %s overlap", a.Node.Id, b.Node.Id)
+			}
+		}
+	}
+
+	// the edge to the unknown node is dropped and the back edge is kept
+	if len(layout.Edges) != 5 {
+		t.Fatalf("expected 5 edges, got %d", len(layout.Edges))
+	}
+
+	// the long root -> leaf edge is routed through a dummy vertex and the
+	// reversed back edge still ends at root
+	for _, edge := range layout.Edges {
+		if edge.Edge.Source == "root" && edge.Edge.Target == "leaf" && len(edge.Points) != 3 {
+			t.Fatalf("long edge was not split: %+v", edge.Points)
+		}
+		if edge.Edge.Source == "leaf" && edge.Edge.Target == "root" {
+			end := edge.Points[len(edge.Points)-1]
+			if end[1] != nodes["root"].Y+nodes["root"].Height/2 {
+				t.Fatalf("back edge does not end at root: %+v", edge.Points)
+			}
+		}
+	}
+}
+
+func Test_LayoutEmptyGraph(t *testing.T) {
+	layout := layoutGraph(&Graph{})
+	if layout.Width <= 0 || layout.Height <= 0 || len(layout.Nodes) != 0 {
+		t.Fatalf("unexpected layout for empty graph: %+v", layout)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ContainerDependency is one reason Container needs DependsOn to be up
+// first: a link, a shared namespace, volumes-from or a Compose depends_on.
+type ContainerDependency struct {
+	Container string
+	DependsOn string
+	Kind      string
+}
+
+// collectContainerDependencies gathers everything that ties one container's
+// start to another's, with each reason listed once.
+func collectContainerDependencies(containers *[]Container, OnlyRunning bool) []ContainerDependency {
+	var dependencies []ContainerDependency
+	seen := make(map[ContainerDependency]bool)
+	add := func(dependency ContainerDependency) {
+		if !seen[dependency] {
+			seen[dependency] = true
+			dependencies = append(dependencies, dependency)
+		}
+	}
+
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		add(ContainerDependency{link.Target, link.Source, "link"})
+	}
+	for _, share := range collectContainerShares(containers, OnlyRunning) {
+		add(ContainerDependency{share.Target, share.Source, share.Kind})
+	}
+
+	// depends_on names services, which may have several containers
+	byService := make(map[string][]string)
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		if project := container.Labels[composeProjectLabel]; project != "" {
+			key := project + "/" + container.Labels[composeServiceLabel]
+			byService[key] = append(byService[key], primaryContainerName(container))
+		}
+	}
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		project := container.Labels[composeProjectLabel]
+		for _, service := range composeDependsOn(container) {
+			for _, name := range byService[project+"/"+service] {
+				add(ContainerDependency{primaryContainerName(container), name, "depends_on"})
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// composeDependsOn reads the services a Compose container depends on.  Newer
+// versions of Compose record them as "service:condition:restart".
+func composeDependsOn(container Container) []string {
+	var services []string
+	for _, entry := range strings.Split(container.Labels[composeDependsOnLabel], ",") {
+		if service := strings.TrimSpace(strings.SplitN(entry, ":", 2)[0]); service != "" {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+// orderContainers sorts the containers so each one comes after everything it
+// depends on, taking them alphabetically when there is a choice.  Containers
+// caught in or waiting on a cycle are left out of the order, and each cycle
+// is returned as a path that starts and ends on the same container.
+func orderContainers(names []string, dependencies []ContainerDependency) ([]string, [][]string) {
+	requires := make(map[string]map[string]bool)
+	dependents := make(map[string][]string)
+	for _, name := range names {
+		requires[name] = make(map[string]bool)
+	}
+	for _, dependency := range dependencies {
+		if _, ok := requires[dependency.Container]; !ok {
+			continue
+		}
+		if _, ok := requires[dependency.DependsOn]; !ok {
+			continue
+		}
+		if !requires[dependency.Container][dependency.DependsOn] {
+			requires[dependency.Container][dependency.DependsOn] = true
+			dependents[dependency.DependsOn] = append(dependents[dependency.DependsOn], dependency.Container)
+		}
+	}
+
+	waiting := make(map[string]int)
+	var ready []string
+	for _, name := range names {
+		waiting[name] = len(requires[name])
+		if waiting[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	var order []string
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependent := range dependents[name] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	var cycles [][]string
+	for _, component := range stronglyConnected(names, requires) {
+		start := component[0]
+		if len(component) == 1 && !requires[start][start] {
+			continue
+		}
+		members := make(map[string]bool)
+		for _, name := range component {
+			members[name] = true
+		}
+		cycles = append(cycles, cyclePath(start, start, requires, members, map[string]bool{}))
+	}
+
+	return order, cycles
+}
+
+// stronglyConnected splits the graph into groups of containers that all
+// depend on each other (Tarjan's algorithm), each sorted by name.
+func stronglyConnected(names []string, requires map[string]map[string]bool) [][]string {
+	index := make(map[string]int)
+	lowest := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = len(index)
+		lowest[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, next := range sortedKeys(requires[name]) {
+			if _, visited := index[next]; !visited {
+				visit(next)
+				if lowest[next] < lowest[name] {
+					lowest[name] = lowest[next]
+				}
+			} else if onStack[next] && index[next] < lowest[name] {
+				lowest[name] = index[next]
+			}
+		}
+
+		if lowest[name] == index[name] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == name {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if _, visited := index[name]; !visited {
+			visit(name)
+		}
+	}
+
+	sort.Slice(components, func(a, b int) bool {
+		return components[a][0] < components[b][0]
+	})
+	return components
+}
+
+// cyclePath follows dependencies within a cycle from name until it gets back
+// to start.
+func cyclePath(name string, start string, requires map[string]map[string]bool, members map[string]bool, visited map[string]bool) []string {
+	visited[name] = true
+	for _, next := range sortedKeys(requires[name]) {
+		if next == start {
+			return []string{name, start}
+		}
+		if members[next] && !visited[next] {
+			if path := cyclePath(next, start, requires, members, visited); path != nil {
+				return append([]string{name}, path...)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonContainersToOrder prints the order to start the containers in, and
+// the reverse to stop them, followed by any dependency cycles.  It returns
+// an error if there are cycles, since no order is then safe.
+func jsonContainersToOrder(containers *[]Container, OnlyRunning bool) (string, error) {
+	var buffer bytes.Buffer
+
+	var names []string
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		names = append(names, primaryContainerName(container))
+	}
+	dependencies := collectContainerDependencies(containers, OnlyRunning)
+	order, cycles := orderContainers(names, dependencies)
+
+	reasons := make(map[string][]string)
+	for _, dependency := range dependencies {
+		reasons[dependency.Container] = append(reasons[dependency.Container], fmt.Sprintf("%s (%s)", dependency.DependsOn, dependency.Kind))
+	}
+
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "START\tNAME\tDEPENDS ON")
+	for index, name := range order {
+		dependsOn := "-"
+		if len(reasons[name]) > 0 {
+			dependsOn = strings.Join(reasons[name], ", ")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", index+1, name, dependsOn)
+	}
+	writer.Flush()
+
+	buffer.WriteString("\n")
+	writer = tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "STOP\tNAME")
+	for index := range order {
+		fmt.Fprintf(writer, "%d\t%s\n", index+1, order[len(order)-1-index])
+	}
+	writer.Flush()
+
+	if len(cycles) == 0 {
+		return buffer.String(), nil
+	}
+
+	ordered := make(map[string]bool)
+	for _, name := range order {
+		ordered[name] = true
+	}
+	var unordered []string
+	for _, name := range names {
+		if !ordered[name] {
+			unordered = append(unordered, name)
+		}
+	}
+
+	buffer.WriteString("\nDependency cycles:\n")
+	for _, cycle := range cycles {
+		buffer.WriteString("  " + strings.Join(cycle, " -> ") + "\n")
+	}
+	buffer.WriteString("Not ordered: " + strings.Join(unordered, ", ") + "\n")
+
+	return buffer.String(), fmt.Errorf("Found %d dependency cycle(s), so not every container could be ordered", len(cycles))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+)
+
+const dependentContainersJSON = `[` +
+	`{"Status":"Up 1 minute","Names":["/shop-web-1"],"Image":"nginx:latest","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"nginx","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"web","com.docker.compose.depends_on":"api:service_started:false"}},` +
+	`{"Status":"Up 1 minute","Names":["/shop-api-1"],"Image":"shop/api:1.0","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985982,"Command":"api","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"api","com.docker.compose.depends_on":"db:service_healthy:true,cache:service_started:false"}},` +
+	`{"Status":"Up 1 minute","Names":["/shop-db-1"],"Image":"postgres:15","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399985981,"Command":"postgres","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"db"}},` +
+	`{"Status":"Up 1 minute","Names":["/shop-cache-1"],"Image":"redis:7","Id":"4f3b7d6a8e5c2d1b0a9f8e7d6c5b4a3928171615141312111000f0e0d0c0b0a09","Created":1399985980,"Command":"redis-server","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"cache"}},` +
+	`{"Status":"Up 1 minute","Names":["/proxy"],"Image":"envoy:1.25","Id":"5a4c8e7b9f6d3e2c1b0a9f8e7d6c5b4a39281716151413121110f0e0d0c0b0a0","Created":1399985979,"Command":"envoy","HostConfig":{"NetworkMode":"container:shop-web-1"}},` +
+	`{"Status":"Up 1 minute","Names":["/metrics","/grafana/metrics"],"Image":"prom/prometheus:latest","Id":"6b5d9f8c0a7e4f3d2c1b0a9f8e7d6c5b4a3928171615141312110f0e0d0c0b0a","Created":1399985978,"Command":"prometheus"},` +
+	`{"Status":"Up 1 minute","Names":["/grafana"],"Image":"grafana/grafana:latest","Id":"7c6e0a9d1b8f5a4e3d2c1b0a9f8e7d6c5b4a39281716151413120f0e0d0c0b0a","Created":1399985977,"Command":"grafana"}` +
+	`]`
+
+const cyclicContainersJSON = `[` +
+	`{"Status":"Up 1 minute","Names":["/a"],"Image":"busybox:latest","Id":"a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","Created":1399985983,"Command":"sh","Labels":{"com.docker.compose.project":"loop","com.docker.compose.service":"a","com.docker.compose.depends_on":"b"}},` +
+	`{"Status":"Up 1 minute","Names":["/b"],"Image":"busybox:latest","Id":"b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1","Created":1399985983,"Command":"sh","Labels":{"com.docker.compose.project":"loop","com.docker.compose.service":"b","com.docker.compose.depends_on":"a"}},` +
+	`{"Status":"Up 1 minute","Names":["/c"],"Image":"busybox:latest","Id":"c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2","Created":1399985983,"Command":"sh","Labels":{"com.docker.compose.project":"loop","com.docker.compose.service":"c","com.docker.compose.depends_on":"a"}},` +
+	`{"Status":"Up 1 minute","Names":["/d"],"Image":"busybox:latest","Id":"d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2b2b2b2b2c3c3","Created":1399985983,"Command":"sh"}` +
+	`]`
+
+func Test_ContainersOrder(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(dependentContainersJSON))
+
+	result, err := jsonContainersToOrder(containers, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "START  NAME          DEPENDS ON\n" +
+		"1      metrics       -\n" +
+		"2      grafana       metrics (link)\n" +
+		"3      shop-cache-1  -\n" +
+		"4      shop-db-1     -\n" +
+		"5      shop-api-1    shop-db-1 (depends_on), shop-cache-1 (depends_on)\n" +
+		"6      shop-web-1    shop-api-1 (depends_on)\n" +
+		"7      proxy         shop-web-1 (network)\n" +
+		"\n" +
+		"STOP  NAME\n" +
+		"1     proxy\n" +
+		"2     shop-web-1\n" +
+		"3     shop-api-1\n" +
+		"4     shop-db-1\n" +
+		"5     shop-cache-1\n" +
+		"6     grafana\n" +
+		"7     metrics\n"
+	if result != expected {
+		t.Fatalf("containers order '%s' did not match '%s'", result, expected)
+	}
+}
+
+func Test_ContainersOrderCycle(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(cyclicContainersJSON))
+
+	result, err := jsonContainersToOrder(containers, false)
+	if err == nil {
+		t.Fatalf("containers order '%s' should have failed on the cycle", result)
+	}
+	expected := "START  NAME  DEPENDS ON\n" +
+		"1      d     -\n" +
+		"\n" +
+		"STOP  NAME\n" +
+		"1     d\n" +
+		"\n" +
+		"Dependency cycles:\n" +
+		"  a -> b -> a\n" +
+		"Not ordered: a, b, c\n"
+	if result != expected {
+		t.Fatalf("containers order '%s' did not match '%s'", result, expected)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// PublishedPort is one port a container exposes, and where on the host (if
+// anywhere) it is published.
+type PublishedPort struct {
+	Container   string
+	IP          string
+	PublicPort  int
+	PrivatePort int
+	Type        string
+	Conflict    bool
+}
+
+// Binding says how reachable a published port is: "all" interfaces,
+// "loopback" only, a "specific" address, or "unpublished".
+func (p PublishedPort) Binding() string {
+	if p.PublicPort == 0 {
+		return "unpublished"
+	}
+	switch {
+	case p.IP == "" || p.IP == "0.0.0.0" || p.IP == "::":
+		return "all"
+	case strings.HasPrefix(p.IP, "127.") || p.IP == "::1":
+		return "loopback"
+	}
+	return "specific"
+}
+
+func (p PublishedPort) HostAddress() string {
+	ip := p.IP
+	if ip == "" {
+		ip = "0.0.0.0"
+	}
+	if strings.Contains(ip, ":") {
+		return fmt.Sprintf("[%s]:%d/%s", ip, p.PublicPort, p.Type)
+	}
+	return fmt.Sprintf("%s:%d/%s", ip, p.PublicPort, p.Type)
+}
+
+func (p PublishedPort) ContainerPort() string {
+	return fmt.Sprintf("%d/%s", p.PrivatePort, p.Type)
+}
+
+var portBindingColors = map[string]string{"all": "lightsalmon", "loopback": "palegreen", "specific": "lightyellow"}
+
+func jsonContainerPortsToDot(containers *[]Container, OnlyRunning bool) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph docker {\n")
+	buffer.WriteString(" rankdir=LR;\n")
+
+	shown := make(map[string]bool)
+	for _, port := range collectPublishedPorts(containers, OnlyRunning) {
+		if port.PublicPort == 0 {
+			continue
+		}
+
+		hostAddress := port.HostAddress()
+		if !shown[hostAddress] {
+			shown[hostAddress] = true
+			fillColor := portBindingColors[port.Binding()]
+			if port.Conflict {
+				fillColor = "red"
+			}
+			buffer.WriteString(fmt.Sprintf(" \"host:%s\" [label=\"%s\\n%s\",shape=box,fillcolor=\"%s\",style=\"filled\"];\n", hostAddress, hostAddress, port.Binding(), fillColor))
+		}
+
+		edgeColor := "black"
+		if port.Conflict {
+			edgeColor = "red"
+		}
+		buffer.WriteString(fmt.Sprintf(" \"host:%s\" -> \"%s\" [label = \" %s\",color=\"%s\" ]\n", hostAddress, port.Container, port.ContainerPort(), edgeColor))
+
+		if !shown[port.Container] {
+			shown[port.Container] = true
+			buffer.WriteString(fmt.Sprintf(" \"%s\" [shape=box,fillcolor=\"paleturquoise\",style=\"filled,rounded\"];\n", port.Container))
+		}
+	}
+
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+func jsonContainerPortsToTable(containers *[]Container, OnlyRunning bool) string {
+	var buffer bytes.Buffer
+
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "HOST\tCONTAINER\tPORT\tBINDING")
+	for _, port := range collectPublishedPorts(containers, OnlyRunning) {
+		hostAddress := "-"
+		if port.PublicPort != 0 {
+			hostAddress = port.HostAddress()
+		}
+		binding := port.Binding()
+		if port.Conflict {
+			binding = binding + " CONFLICT"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", hostAddress, port.Container, port.ContainerPort(), binding)
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
+
+// collectPublishedPorts lists the ports of the shown containers, ordered by
+// host port, marking ports that more than one container publishes on the
+// same host address.  A port published on all interfaces clashes with the
+// same port on any other address of that family.
+func collectPublishedPorts(containers *[]Container, OnlyRunning bool) []PublishedPort {
+	var ports []PublishedPort
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		containerName := primaryContainerName(container)
+		for _, port := range container.Ports {
+			if port == nil {
+				continue
+			}
+			ports = append(ports, PublishedPort{
+				Container:   containerName,
+				IP:          portField(port, "IP"),
+				PublicPort:  portNumber(port, "PublicPort"),
+				PrivatePort: portNumber(port, "PrivatePort"),
+				Type:        portField(port, "Type"),
+			})
+		}
+	}
+
+	for a := range ports {
+		for b := range ports {
+			if ports[a].Container != ports[b].Container && portsClash(ports[a], ports[b]) {
+				ports[a].Conflict = true
+			}
+		}
+	}
+
+	sort.SliceStable(ports, func(a, b int) bool {
+		if ports[a].PublicPort != ports[b].PublicPort {
+			return ports[a].PublicPort < ports[b].PublicPort
+		}
+		if ports[a].IP != ports[b].IP {
+			return ports[a].IP < ports[b].IP
+		}
+		return ports[a].Container < ports[b].Container
+	})
+
+	return ports
+}
+
+func portsClash(a PublishedPort, b PublishedPort) bool {
+	if a.PublicPort == 0 || a.PublicPort != b.PublicPort || a.Type != b.Type {
+		return false
+	}
+	if a.IP == b.IP {
+		return true
+	}
+	if strings.Contains(a.IP, ":") != strings.Contains(b.IP, ":") {
+		return false
+	}
+	return a.Binding() == "all" || b.Binding() == "all"
+}
+
+func portField(port map[string]interface{}, key string) string {
+	if value, ok := port[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// portNumber reads a port whether it came from the API client (an integer)
+// or from JSON on stdin (a float64).
+func portNumber(port map[string]interface{}, key string) int {
+	number, _ := strconv.ParseFloat(portField(port, key), 64)
+	return int(number)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const portsJSON = `[{"Status":"Up 1 minute","Names":["/web"],"Image":"nginx:latest","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"nginx","Ports":[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":8080,"Type":"tcp"},{"IP":"::","PrivatePort":80,"PublicPort":8080,"Type":"tcp"},{"PrivatePort":443,"Type":"tcp"}]},{"Status":"Up 1 minute","Names":["/admin"],"Image":"shop/admin:1.0","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"admin","Ports":[{"IP":"127.0.0.1","PrivatePort":3000,"PublicPort":8080,"Type":"tcp"},{"IP":"127.0.0.1","PrivatePort":3000,"PublicPort":3000,"Type":"udp"}]},{"Status":"Up 1 minute","Names":["/db"],"Image":"postgres:15","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399984760,"Command":"postgres","Ports":[{"IP":"127.0.0.1","PrivatePort":5432,"PublicPort":5432,"Type":"tcp"}]}]`
+
+func Test_PortsTable(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(portsJSON))
+
+	result := jsonContainerPortsToTable(containers, false)
+	expected := "HOST                CONTAINER  PORT      BINDING\n" +
+		"-                   web        443/tcp   unpublished\n" +
+		"127.0.0.1:3000/udp  admin      3000/udp  loopback\n" +
+		"127.0.0.1:5432/tcp  db         5432/tcp  loopback\n" +
+		"0.0.0.0:8080/tcp    web        80/tcp    all CONFLICT\n" +
+		"127.0.0.1:8080/tcp  admin      3000/tcp  loopback CONFLICT\n" +
+		"[::]:8080/tcp       web        80/tcp    all\n"
+	if result != expected {
+		t.Fatalf("ports table '%s' did not match '%s'", result, expected)
+	}
+}
+
+func Test_PortsDot(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(portsJSON))
+
+	result := jsonContainerPortsToDot(containers, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?s)digraph docker {.*}`,
+		`(?m)^ "host:0.0.0.0:8080/tcp" \[label="0.0.0.0:8080/tcp\\nall",shape=box,fillcolor="red"`,
+		`(?m)^ "host:127.0.0.1:5432/tcp" \[label="127.0.0.1:5432/tcp\\nloopback",shape=box,fillcolor="palegreen"`,
+		`(?m)^ "host:\[::\]:8080/tcp" \[label="\[::\]:8080/tcp\\nall",shape=box,fillcolor="lightsalmon"`,
+		`(?m)^ "host:127.0.0.1:8080/tcp" -> "admin" \[label = " 3000/tcp",color="red" \]$`,
+		`(?m)^ "host:127.0.0.1:5432/tcp" -> "db" \[label = " 5432/tcp",color="black" \]$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("ports dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	if strings.Contains(result, "443") {
+		t.Fatalf("ports dot content '%s' should not contain unpublished ports", result)
+	}
+}
+
+func Test_APIPortToMap(t *testing.T) {
+	if ports := apiPortToMap(nil); len(ports) != 0 {
+		t.Fatalf("apiPortToMap of no ports returned '%v'", ports)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ContainerConfig is the part of a container's config only inspecting it
+// gives.  It is left nil for containers read from stdin without it, so they
+// are not taken to be running as root.
+type ContainerConfig struct {
+	User string
+}
+
+// ContainerRisk is something about how a container was started that weakens
+// its isolation from the host, like --privileged or the Docker socket.
+type ContainerRisk struct {
+	Kind   string
+	Detail string `json:",omitempty"`
+}
+
+// kinds of risk, as used with --allow
+var riskKinds = []string{"privileged", "host-network", "host-pid", "host-ipc", "cap-add", "docker-socket", "no-seccomp", "no-apparmor", "root"}
+
+func (r ContainerRisk) String() string {
+	if r.Detail != "" {
+		return r.Kind + " " + r.Detail
+	}
+	return r.Kind
+}
+
+func containerConfig(details *docker.Container) *ContainerConfig {
+	if details.Config == nil {
+		return nil
+	}
+	return &ContainerConfig{details.Config.User}
+}
+
+// containerRisks lists the ways a container is less isolated than docker's
+// defaults, in the order of riskKinds.
+func containerRisks(container Container) []ContainerRisk {
+	var risks []ContainerRisk
+	config := container.HostConfig
+
+	if config.Privileged {
+		risks = append(risks, ContainerRisk{Kind: "privileged"})
+	}
+	if config.NetworkMode == "host" {
+		risks = append(risks, ContainerRisk{Kind: "host-network"})
+	}
+	if config.PidMode == "host" {
+		risks = append(risks, ContainerRisk{Kind: "host-pid"})
+	}
+	if config.IpcMode == "host" {
+		risks = append(risks, ContainerRisk{Kind: "host-ipc"})
+	}
+	if len(config.CapAdd) > 0 {
+		capabilities := append([]string{}, config.CapAdd...)
+		sort.Strings(capabilities)
+		risks = append(risks, ContainerRisk{"cap-add", strings.Join(capabilities, ",")})
+	}
+	for _, mount := range container.Mounts {
+		if exposesDockerSocket(mount) {
+			risks = append(risks, ContainerRisk{Kind: "docker-socket"})
+			break
+		}
+	}
+
+	// options are written "seccomp=unconfined", or with a colon by older
+	// clients
+	for _, option := range config.SecurityOpt {
+		switch strings.Replace(option, ":", "=", 1) {
+		case "seccomp=unconfined":
+			risks = append(risks, ContainerRisk{Kind: "no-seccomp"})
+		case "apparmor=unconfined":
+			risks = append(risks, ContainerRisk{Kind: "no-apparmor"})
+		}
+	}
+
+	if container.Config != nil && runsAsRoot(container.Config.User) {
+		risks = append(risks, ContainerRisk{Kind: "root"})
+	}
+
+	return risks
+}
+
+// exposesDockerSocket checks for the socket itself, or for a bind mount of
+// the directory it is in, which hands it over just the same.
+func exposesDockerSocket(mount ContainerMount) bool {
+	if path.Base(mount.Source) == "docker.sock" {
+		return true
+	}
+	if mount.Type != "" && mount.Type != "bind" {
+		return false
+	}
+	source := path.Clean(mount.Source)
+	return source == "/var/run" || source == "/run"
+}
+
+// runsAsRoot checks a --user value, which is root when empty or when it
+// names root by name or UID, with or without a group.
+func runsAsRoot(user string) bool {
+	user = strings.SplitN(user, ":", 2)[0]
+	return user == "" || user == "root" || user == "0"
+}
+
+// containerRiskList joins a container's risks for columns and attributes
+// that are already known to be about risk.
+func containerRiskList(container Container) string {
+	var parts []string
+	for _, risk := range containerRisks(container) {
+		parts = append(parts, risk.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func containerRiskLabel(container Container) string {
+	if risks := containerRiskList(container); risks != "" {
+		return "Risk: " + risks
+	}
+	return ""
+}
+
+// parseAllowedRisks checks the risk kinds given to --allow.
+func parseAllowedRisks(allow []string) (map[string]bool, error) {
+	allowed := make(map[string]bool)
+	for _, value := range allow {
+		for _, kind := range strings.Split(value, ",") {
+			kind = strings.TrimSpace(kind)
+			known := false
+			for _, riskKind := range riskKinds {
+				if kind == riskKind {
+					known = true
+				}
+			}
+			if !known {
+				return nil, fmt.Errorf("Unknown risk '%s', expected one of: %s", kind, strings.Join(riskKinds, ", "))
+			}
+			allowed[kind] = true
+		}
+	}
+	return allowed, nil
+}
+
+// jsonContainersToAudit lists each container with a risk, marking the ones
+// the policy allows, and returns how many containers break the policy.
+func jsonContainersToAudit(containers *[]Container, OnlyRunning bool, allowed map[string]bool) (string, int) {
+	var buffer bytes.Buffer
+
+	violations := 0
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tIMAGE\tSTATUS\tRISKS")
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		risks := containerRisks(container)
+		if len(risks) == 0 {
+			continue
+		}
+
+		violates := false
+		var parts []string
+		for _, risk := range risks {
+			if allowed[risk.Kind] {
+				parts = append(parts, risk.String()+" (allowed)")
+			} else {
+				parts = append(parts, risk.String())
+				violates = true
+			}
+		}
+		if violates {
+			violations++
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", primaryContainerName(container), container.Image, container.Status, strings.Join(parts, ", "))
+	}
+	writer.Flush()
+
+	return buffer.String(), violations
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+const riskyContainersJSON = `[` +
+	`{"Status":"Up 1 minute","Names":["/agent"],"Image":"portainer/agent:2.19","Id":"a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","Created":1399985983,"Command":"./agent","Mounts":[{"Type":"bind","Source":"/var/run/docker.sock","Destination":"/var/run/docker.sock","RW":true}],"HostConfig":{"NetworkMode":"host","PidMode":"host","Privileged":true},"Config":{"User":""}},` +
+	`{"Status":"Up 1 minute","Names":["/vpn"],"Image":"wireguard:latest","Id":"b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1","Created":1399985984,"Command":"wg","HostConfig":{"NetworkMode":"bridge","CapAdd":["SYS_MODULE","NET_ADMIN"],"SecurityOpt":["seccomp=unconfined","apparmor:unconfined"]},"Config":{"User":"1000:1000"}},` +
+	`{"Status":"Up 1 minute","Names":["/web"],"Image":"nginx:latest","Id":"c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2","Created":1399985985,"Command":"nginx","HostConfig":{"NetworkMode":"bridge"},"Config":{"User":"root"}},` +
+	`{"Status":"Up 1 minute","Names":["/app"],"Image":"shop/app:1.0","Id":"d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2b2b2b2b2c3c3","Created":1399985986,"Command":"app","HostConfig":{"NetworkMode":"bridge"},"Config":{"User":"app"}},` +
+	`{"Status":"Up 1 minute","Names":["/listed"],"Image":"busybox:latest","Id":"e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4","Created":1399985987,"Command":"sh","HostConfig":{"NetworkMode":"bridge"}}` +
+	`]`
+
+func Test_ContainersAudit(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(riskyContainersJSON))
+
+	result, violations := jsonContainersToAudit(containers, false, map[string]bool{})
+	expected := "NAME   IMAGE                 STATUS       RISKS\n" +
+		"agent  portainer/agent:2.19  Up 1 minute  privileged, host-network, host-pid, docker-socket, root\n" +
+		"vpn    wireguard:latest      Up 1 minute  cap-add NET_ADMIN,SYS_MODULE, no-seccomp, no-apparmor\n" +
+		"web    nginx:latest          Up 1 minute  root\n"
+	if result != expected || violations != 3 {
+		t.Fatalf("containers audit '%s' (%d violations) did not match '%s'", result, violations, expected)
+	}
+
+	allowed, err := parseAllowedRisks([]string{"root", "cap-add,no-seccomp,no-apparmor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, violations = jsonContainersToAudit(containers, false, allowed)
+	expected = "NAME   IMAGE                 STATUS       RISKS\n" +
+		"agent  portainer/agent:2.19  Up 1 minute  privileged, host-network, host-pid, docker-socket, root (allowed)\n" +
+		"vpn    wireguard:latest      Up 1 minute  cap-add NET_ADMIN,SYS_MODULE (allowed), no-seccomp (allowed), no-apparmor (allowed)\n" +
+		"web    nginx:latest          Up 1 minute  root (allowed)\n"
+	if result != expected || violations != 1 {
+		t.Fatalf("containers audit '%s' (%d violations) did not match '%s'", result, violations, expected)
+	}
+
+	if _, err := parseAllowedRisks([]string{"everything"}); err == nil {
+		t.Fatalf("unknown risk should not have been allowed")
+	}
+}
+
+func Test_ContainersRisks(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(riskyContainersJSON))
+
+	result := jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "agent" \[label="portainer/agent:2.19\\nagent\\na1a1a1a1a1a1\\nRisk: privileged, host-network, host-pid, docker-socket, root",`,
+		`(?m)^ "app" \[label="shop/app:1.0\\napp\\nd4d4d4d4d4d4",`,
+		`(?m)^ "listed" \[label="busybox:latest\\nlisted\\ne5e5e5e5e5e5",`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	result = jsonContainersToTree(containers, false, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^│ └─web c3c3c3c3c3c3 Up 1 minute Risk: root$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	result, _ = jsonContainersToJSON(containers, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`"Risks": \[\s+\{\s+"Kind": "cap-add",\s+"Detail": "NET_ADMIN,SYS_MODULE"\s+\},\s+\{\s+"Kind": "no-seccomp"\s+\}`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers json content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
+
+// Test_ContainersDockerSocket checks that mounting the directory the socket
+// is in counts the same as mounting the socket.
+func Test_ContainersDockerSocket(t *testing.T) {
+	socketJSON := `[` +
+		`{"Status":"Up 1 minute","Names":["/run"],"Image":"busybox:latest","Id":"a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","Created":1399985983,"Command":"sh","Mounts":[{"Type":"bind","Source":"/run","Destination":"/host/run"}]},` +
+		`{"Status":"Up 1 minute","Names":["/varrun"],"Image":"busybox:latest","Id":"b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1","Created":1399985984,"Command":"sh","Mounts":[{"Type":"bind","Source":"/var/run/","Destination":"/var/run"}]},` +
+		`{"Status":"Up 1 minute","Names":["/volume"],"Image":"busybox:latest","Id":"c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2","Created":1399985985,"Command":"sh","Mounts":[{"Type":"volume","Name":"run","Source":"/var/lib/docker/volumes/run/_data","Destination":"/run"}]}` +
+		`]`
+	containers, _ := parseContainersJSON([]byte(socketJSON))
+
+	columns, _ := parseColumns("name,risks", containerColumns)
+	result, err := jsonContainersToTable(containers, false, columns, ',')
+	if err != nil {
+		t.Fatalf("containers table failed: %s", err)
+	}
+	expected := "name,risks\n" +
+		"run,docker-socket\n" +
+		"varrun,docker-socket\n" +
+		"volume,\n"
+	if result != expected {
+		t.Fatalf("containers table '%s' did not match '%s'", result, expected)
+	}
+}
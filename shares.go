@@ -0,0 +1,136 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"sort"
+	"strings"
+)
+
+// ContainerHostConfig is the part of a container's host config that ties it
+// to other containers or loosens its isolation from the host.
+type ContainerHostConfig struct {
+	NetworkMode string
+	PidMode     string
+	IpcMode     string
+	VolumesFrom []string `json:",omitempty"`
+	Privileged  bool     `json:",omitempty"`
+	CapAdd      []string `json:",omitempty"`
+	SecurityOpt []string `json:",omitempty"`
+}
+
+// ContainerShare is a container using a namespace or the volumes of
+// another, so Target breaks when Source is stopped.
+type ContainerShare struct {
+	Source string
+	Target string
+	Kind   string
+}
+
+// kinds of sharing, in the order they are listed
+var shareKinds = []string{"network", "pid", "ipc", "volumes-from"}
+
+var shareLabels = map[string]string{
+	"network":      "network namespace",
+	"pid":          "pid namespace",
+	"ipc":          "ipc namespace",
+	"volumes-from": "volumes from",
+}
+
+var shareColors = map[string]string{
+	"network":      "blue",
+	"pid":          "purple",
+	"ipc":          "darkgreen",
+	"volumes-from": "saddlebrown",
+}
+
+func (s ContainerShare) label() string {
+	return shareLabels[s.Kind]
+}
+
+func containerHostConfig(details *docker.Container) ContainerHostConfig {
+	if details.HostConfig == nil {
+		return ContainerHostConfig{}
+	}
+	return ContainerHostConfig{
+		details.HostConfig.NetworkMode,
+		details.HostConfig.PidMode,
+		details.HostConfig.IpcMode,
+		details.HostConfig.VolumesFrom,
+		details.HostConfig.Privileged,
+		details.HostConfig.CapAdd,
+		details.HostConfig.SecurityOpt,
+	}
+}
+
+// sharedContainers returns the containers referred to by each kind of
+// sharing, as the name or ID given when the container was created.
+func sharedContainers(config ContainerHostConfig) map[string][]string {
+	refs := make(map[string][]string)
+	for kind, mode := range map[string]string{"network": config.NetworkMode, "pid": config.PidMode, "ipc": config.IpcMode} {
+		if strings.HasPrefix(mode, "container:") {
+			refs[kind] = append(refs[kind], strings.TrimPrefix(mode, "container:"))
+		}
+	}
+	for _, from := range config.VolumesFrom {
+		// drop a trailing :ro or :rw
+		refs["volumes-from"] = append(refs["volumes-from"], strings.SplitN(from, ":", 2)[0])
+	}
+	return refs
+}
+
+// collectContainerShares finds containers sharing the network, PID or IPC
+// namespace of another container, or mounting its volumes with
+// --volumes-from.  Containers are referred to by name or by ID, which
+// dockviz resolves to the name of a container being shown.
+func collectContainerShares(containers *[]Container, OnlyRunning bool) []ContainerShare {
+	var shown []Container
+	for _, container := range *containers {
+		if OnlyRunning && strings.HasPrefix(container.Status, "Exit") {
+			continue
+		}
+		shown = append(shown, container)
+	}
+
+	var shares []ContainerShare
+	for _, container := range shown {
+		refs := sharedContainers(container.HostConfig)
+		for _, kind := range shareKinds {
+			for _, ref := range refs[kind] {
+				if source := resolveContainer(shown, ref); source != "" {
+					shares = append(shares, ContainerShare{source, primaryContainerName(container), kind})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(shares, func(a, b int) bool {
+		if shares[a].Target != shares[b].Target {
+			return shares[a].Target < shares[b].Target
+		}
+		return shares[a].Source < shares[b].Source
+	})
+
+	return shares
+}
+
+// resolveContainer finds the name of the container a reference points to,
+// by name, full ID or ID prefix.
+func resolveContainer(containers []Container, ref string) string {
+	ref = strings.TrimPrefix(ref, "/")
+	for _, container := range containers {
+		for _, name := range container.Names {
+			if strings.TrimPrefix(name, "/") == ref {
+				return primaryContainerName(container)
+			}
+		}
+	}
+	if len(ref) >= 12 {
+		for _, container := range containers {
+			if strings.HasPrefix(container.Id, ref) {
+				return primaryContainerName(container)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const sharingContainersJSON = `[` +
+	`{"Status":"Up 1 minute","Names":["/app"],"Image":"shop/app:1.0","Id":"a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","Created":1399985983,"Command":"app","HostConfig":{"NetworkMode":"bridge"}},` +
+	`{"Status":"Up 1 minute","Names":["/proxy"],"Image":"envoy:1.25","Id":"b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1","Created":1399985984,"Command":"envoy","HostConfig":{"NetworkMode":"container:app"}},` +
+	`{"Status":"Up 1 minute","Names":["/debug"],"Image":"busybox:latest","Id":"c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2","Created":1399985985,"Command":"sh","HostConfig":{"NetworkMode":"bridge","PidMode":"container:a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4d4d4d4d4e5e5e5e5e5e5f6f6","IpcMode":"container:app"}},` +
+	`{"Status":"Exited (0) 1 minute ago","Names":["/data"],"Image":"busybox:latest","Id":"d4d4d4d4d4d4e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2b2b2b2b2c3c3","Created":1399985900,"Command":"true","HostConfig":{"NetworkMode":"none"}},` +
+	`{"Status":"Up 1 minute","Names":["/backup"],"Image":"shop/backup:1.0","Id":"e5e5e5e5e5e5f6f6f6f6f6f6a1a1a1a1a1a1b2b2b2b2b2b2c3c3c3c3c3c3d4d4","Created":1399985986,"Command":"backup","HostConfig":{"NetworkMode":"bridge","VolumesFrom":["data:ro","missing"]}}` +
+	`]`
+
+func Test_ContainersShares(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(sharingContainersJSON))
+
+	shares := collectContainerShares(containers, false)
+	expected := []ContainerShare{
+		{"data", "backup", "volumes-from"},
+		{"app", "debug", "pid"},
+		{"app", "debug", "ipc"},
+		{"app", "proxy", "network"},
+	}
+	if len(shares) != len(expected) {
+		t.Fatalf("container shares '%v' did not match '%v'", shares, expected)
+	}
+	for i := range expected {
+		if shares[i] != expected[i] {
+			t.Fatalf("container shares '%v' did not match '%v'", shares, expected)
+		}
+	}
+
+	result := jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "app" -> "proxy" \[label = " network namespace",style=bold,color="blue",`,
+		`(?m)^ "app" -> "debug" \[label = " pid namespace",style=bold,color="purple",`,
+		`(?m)^ "app" -> "debug" \[label = " ipc namespace",style=bold,color="darkgreen",`,
+		`(?m)^ "data" -> "backup" \[label = " volumes from",style=bold,color="saddlebrown",`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	// the volume container is not running, so nothing is drawn to it
+	result = jsonContainersToDot(containers, true, false)
+	if strings.Contains(result, "volumes from") {
+		t.Fatalf("containers dot content '%s' should not have a volumes from edge", result)
+	}
+
+	result = jsonContainersToSVG(containers, false, false)
+	for _, label := range []string{"network namespace", "pid namespace", "ipc namespace", "volumes from"} {
+		if !strings.Contains(result, ">"+label+"</text>") {
+			t.Fatalf("containers svg content '%s' did not label an edge '%s'", result, label)
+		}
+	}
+
+	result = jsonContainersToTree(containers, false, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^│ └─debug c3c3c3c3c3c3 Up 1 minute\n│   ├─pid namespace -> app\n│   └─ipc namespace -> app$`,
+		`(?m)^│ └─proxy b2b2b2b2b2b2 Up 1 minute\n│   └─network namespace -> app$`,
+		`(?m)^  └─backup e5e5e5e5e5e5 Up 1 minute\n    └─volumes from -> data$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
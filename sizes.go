@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const sizeChartWidth = 30
+
+// jsonContainersToSizeChart lists containers by how much they have written
+// to their writable layer, largest first, with a bar for each and the
+// growth compared to the size of the image underneath.
+func jsonContainersToSizeChart(containers *[]Container, OnlyRunning bool) string {
+	var buffer bytes.Buffer
+
+	var sized []Container
+	var largest int64
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		if container.SizeRootFs == 0 && container.SizeRw == 0 {
+			continue
+		}
+		sized = append(sized, container)
+		if container.SizeRw > largest {
+			largest = container.SizeRw
+		}
+	}
+	sort.SliceStable(sized, func(a, b int) bool {
+		return sized[a].SizeRw > sized[b].SizeRw
+	})
+
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tWRITABLE\tIMAGE\tGROWTH")
+	for _, container := range sized {
+		imageSize := container.SizeRootFs - container.SizeRw
+
+		growth := "-"
+		if imageSize > 0 {
+			growth = fmt.Sprintf("%.0f%%", float64(container.SizeRw)/float64(imageSize)*100)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s", primaryContainerName(container), humanSize(container.SizeRw), humanSize(imageSize), growth)
+		if largest > 0 {
+			if bar := strings.Repeat("█", int(float64(container.SizeRw)/float64(largest)*sizeChartWidth+0.5)); bar != "" {
+				fmt.Fprintf(writer, "\t%s", bar)
+			}
+		}
+		fmt.Fprintln(writer)
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
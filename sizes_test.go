@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+)
+
+const sizedContainersJSON = `[{"Status":"Up 1 minute","Names":["/web"],"Image":"nginx:latest","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"nginx","SizeRw":2000000,"SizeRootFs":142000000},{"Status":"Up 1 minute","Names":["/api"],"Image":"shop/api:1.0","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"api","SizeRw":600000000,"SizeRootFs":800000000},{"Status":"Exited (0) 1 minute ago","Names":["/job"],"Image":"shop/job:1.0","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399984760,"Command":"job","SizeRootFs":50000000}]`
+
+func Test_ContainersSizes(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(sizedContainersJSON))
+
+	result := jsonContainersToSizeChart(containers, false)
+	expected := "NAME  WRITABLE  IMAGE     GROWTH\n" +
+		"api   600.0 MB  200.0 MB  300%  ██████████████████████████████\n" +
+		"web   2.0 MB    140.0 MB  1%\n" +
+		"job   0.0 B     50.0 MB   0%\n"
+	if result != expected {
+		t.Fatalf("containers size chart '%s' did not match '%s'", result, expected)
+	}
+
+	result = jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "api" \[label="shop/api:1.0\\napi\\n2d1f5b4e6c3a\\nSize: 600.0 MB \(virtual 800.0 MB\)",`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	result = jsonContainersToTree(containers, true, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^│ └─web 1c0e4a3d5b2f Up 1 minute Size: 2.0 MB \(virtual 142.0 MB\)$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
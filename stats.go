@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"fmt"
+	"time"
+)
+
+// ContainerStats is a one-shot sample of a running container's resource
+// usage.
+type ContainerStats struct {
+	CPUPercent  float64
+	MemoryUsage int64
+	MemoryLimit int64
+	NetworkRx   int64
+	NetworkTx   int64
+}
+
+// collectContainerStats samples every running container concurrently and
+// stores the result on the container.  Containers that go away while being
+// sampled are skipped.
+func collectContainerStats(client *docker.Client, containers *[]Container) error {
+	return forEachConcurrently(len(*containers), func(i int) error {
+		container := &(*containers)[i]
+		if containerState(container.Status) != "running" {
+			return nil
+		}
+
+		sample, err := sampleContainerStats(client, container.Id)
+		if err != nil {
+			if stoppedContainer(err) {
+				return nil
+			}
+			return fmt.Errorf("Error reading stats for %s: %s", primaryContainerName(*container), err)
+		}
+		if sample != nil {
+			stats := statsFromSample(sample)
+			container.Stats = &stats
+		}
+		return nil
+	})
+}
+
+func sampleContainerStats(client *docker.Client, id string) (*docker.Stats, error) {
+	samples := make(chan *docker.Stats, 1)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- client.Stats(docker.StatsOptions{ID: id, Stats: samples, Stream: false, Timeout: 10 * time.Second})
+	}()
+
+	var sample *docker.Stats
+	for s := range samples {
+		if sample == nil {
+			sample = s
+		}
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return sample, nil
+}
+
+// statsFromSample works out usage the way docker stats does: CPU from the
+// change since the previous sample, and memory without the page cache.
+func statsFromSample(sample *docker.Stats) ContainerStats {
+	var stats ContainerStats
+
+	cpuDelta := float64(sample.CPUStats.CPUUsage.TotalUsage) - float64(sample.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(sample.CPUStats.SystemCPUUsage) - float64(sample.PreCPUStats.SystemCPUUsage)
+	cpus := float64(sample.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(sample.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	cache := sample.MemoryStats.Stats.TotalInactiveFile
+	if cache == 0 {
+		cache = sample.MemoryStats.Stats.InactiveFile
+	}
+	if cache < sample.MemoryStats.Usage {
+		stats.MemoryUsage = int64(sample.MemoryStats.Usage - cache)
+	}
+	stats.MemoryLimit = int64(sample.MemoryStats.Limit)
+
+	for _, network := range sample.Networks {
+		stats.NetworkRx = stats.NetworkRx + int64(network.RxBytes)
+		stats.NetworkTx = stats.NetworkTx + int64(network.TxBytes)
+	}
+
+	return stats
+}
+
+func statsLabelParts(stats *ContainerStats) []string {
+	return []string{
+		fmt.Sprintf("CPU %.1f%%", stats.CPUPercent),
+		fmt.Sprintf("Mem %s / %s", humanSize(stats.MemoryUsage), humanSize(stats.MemoryLimit)),
+		fmt.Sprintf("Net %s / %s", humanSize(stats.NetworkRx), humanSize(stats.NetworkTx)),
+	}
+}
+
+// containerHeat scores the sampled containers from 0 to 1 against the
+// busiest one, by CPU, or by memory if nothing is using any CPU.
+func containerHeat(containers []Container) map[string]float64 {
+	var maxCPU float64
+	var maxMemory int64
+	for _, container := range containers {
+		if container.Stats != nil {
+			if container.Stats.CPUPercent > maxCPU {
+				maxCPU = container.Stats.CPUPercent
+			}
+			if container.Stats.MemoryUsage > maxMemory {
+				maxMemory = container.Stats.MemoryUsage
+			}
+		}
+	}
+
+	heat := make(map[string]float64)
+	for _, container := range containers {
+		if container.Stats == nil {
+			continue
+		}
+		if maxCPU > 0 {
+			heat[container.Id] = container.Stats.CPUPercent / maxCPU
+		} else if maxMemory > 0 {
+			heat[container.Id] = float64(container.Stats.MemoryUsage) / float64(maxMemory)
+		} else {
+			heat[container.Id] = 0
+		}
+	}
+	return heat
+}
+
+// heatColor runs from a pale yellow when idle to a strong red for the
+// busiest container.
+func heatColor(heat float64) string {
+	saturation := 0.2 + 0.7*heat
+	hue := 60 * (1 - heat)
+
+	chroma := saturation
+	x := chroma * hue / 60
+	m := 1 - chroma
+
+	return fmt.Sprintf("#%02x%02x%02x", int(255*(chroma+m)+0.5), int(255*(x+m)+0.5), int(255*m+0.5))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"testing"
+)
+
+func Test_StatsFromSample(t *testing.T) {
+	var sample docker.Stats
+	sample.CPUStats.CPUUsage.TotalUsage = 600000000
+	sample.PreCPUStats.CPUUsage.TotalUsage = 400000000
+	sample.CPUStats.SystemCPUUsage = 9000000000
+	sample.PreCPUStats.SystemCPUUsage = 5000000000
+	sample.CPUStats.OnlineCPUs = 4
+	sample.MemoryStats.Usage = 150000000
+	sample.MemoryStats.Limit = 2000000000
+	sample.MemoryStats.Stats.InactiveFile = 30000000
+	sample.Networks = map[string]docker.NetworkStats{
+		"eth0": {RxBytes: 1000000, TxBytes: 200000},
+		"eth1": {RxBytes: 500000, TxBytes: 100000},
+	}
+
+	stats := statsFromSample(&sample)
+	expected := ContainerStats{CPUPercent: 20, MemoryUsage: 120000000, MemoryLimit: 2000000000, NetworkRx: 1500000, NetworkTx: 300000}
+	if stats != expected {
+		t.Fatalf("stats '%+v' did not match '%+v'", stats, expected)
+	}
+
+	parts := statsLabelParts(&stats)
+	if parts[0] != "CPU 20.0%" || parts[1] != "Mem 120.0 MB / 2.0 GB" || parts[2] != "Net 1.5 MB / 300.0 KB" {
+		t.Fatalf("stats label '%v' was not as expected", parts)
+	}
+}
+
+func Test_ContainersStats(t *testing.T) {
+	statsJSON := `[{"Status":"Up 1 minute","Names":["/web"],"Image":"nginx:latest","Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Created":1399985983,"Command":"nginx","Stats":{"CPUPercent":5,"MemoryUsage":20000000,"MemoryLimit":2000000000,"NetworkRx":1000,"NetworkTx":2000}},{"Status":"Up 1 minute","Names":["/api"],"Image":"shop/api:1.0","Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Created":1399985012,"Command":"api","Stats":{"CPUPercent":80,"MemoryUsage":500000000,"MemoryLimit":2000000000,"NetworkRx":3000000,"NetworkTx":4000000}},{"Status":"Exited (0) 1 minute ago","Names":["/job"],"Image":"shop/job:1.0","Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Created":1399984760,"Command":"job"}]`
+	containers, _ := parseContainersJSON([]byte(statsJSON))
+
+	result := jsonContainersToDot(containers, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "api" \[label="shop/api:1.0\\napi\\n2d1f5b4e6c3a\\nCPU 80.0%\\nMem 500.0 MB / 2.0 GB\\nNet 3.0 MB / 4.0 MB",shape=box,fillcolor="#ff1a1a",`,
+		`(?m)^ "web" \[label="nginx:latest\\nweb\\n1c0e4a3d5b2f\\nCPU 5.0%.*",shape=box,fillcolor="#fffbc1",`,
+		`(?m)^ "job" \[label="shop/job:1.0\\njob\\n3e2a6c5f7d4b",shape=box,fillcolor="lightgrey",`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	result = jsonContainersToTree(containers, false, false, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^│ └─web 1c0e4a3d5b2f Up 1 minute CPU 5.0% Mem 20.0 MB / 2.0 GB Net 1.0 KB / 2.0 KB$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("containers tree content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+func graphToSVG(g *Graph) string {
+	var buffer bytes.Buffer
+
+	layout := layoutGraph(g)
+
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	buffer.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\" font-family=\"sans-serif\" font-size=\"12\">\n", layout.Width, layout.Height, layout.Width, layout.Height))
+	buffer.WriteString(" <defs>\n")
+	buffer.WriteString("  <marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\"><path d=\"M0,0 L10,5 L0,10 z\"/></marker>\n")
+	buffer.WriteString(" </defs>\n")
+	buffer.WriteString(" <rect width=\"100%\" height=\"100%\" fill=\"white\"/>\n")
+
+	for _, edge := range layout.Edges {
+		var points []string
+		for _, point := range edge.Points {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", point[0], point[1]))
+		}
+		buffer.WriteString(fmt.Sprintf(" <polyline points=\"%s\" fill=\"none\" stroke=\"black\" marker-end=\"url(#arrow)\"/>\n", strings.Join(points, " ")))
+
+		if edge.Edge.Label != "" && len(edge.Points) > 1 {
+			labelX := (edge.Points[0][0]+edge.Points[1][0])/2 + 4
+			labelY := (edge.Points[0][1] + edge.Points[1][1]) / 2
+			buffer.WriteString(fmt.Sprintf(" <text x=\"%.1f\" y=\"%.1f\">%s</text>\n", labelX, labelY, html.EscapeString(edge.Edge.Label)))
+		}
+	}
+
+	for _, node := range layout.Nodes {
+		fill := node.Node.Fill
+		if fill == "" {
+			fill = "white"
+		}
+		var radius float64
+		if node.Node.Rounded {
+			radius = 6
+		}
+		buffer.WriteString(fmt.Sprintf(" <g id=\"%s\">\n", html.EscapeString(node.Node.Id)))
+		buffer.WriteString(fmt.Sprintf("  <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" rx=\"%.0f\" fill=\"%s\" stroke=\"black\"/>\n", node.X-node.Width/2, node.Y-node.Height/2, node.Width, node.Height, radius, html.EscapeString(fill)))
+		for i, line := range node.Node.Label {
+			lineY := node.Y - node.Height/2 + layoutPadding + float64(i+1)*layoutLineHeight - 4
+			buffer.WriteString(fmt.Sprintf("  <text x=\"%.1f\" y=\"%.1f\" text-anchor=\"middle\">%s</text>\n", node.X, lineY, html.EscapeString(line)))
+		}
+		buffer.WriteString(" </g>\n")
+	}
+
+	buffer.WriteString("</svg>\n")
+
+	return buffer.String()
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/fsouza/go-dockerclient"
+
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Swarm holds the responses of the daemon's /nodes, /services and /tasks
+// endpoints, which is also the shape read from stdin.
+type Swarm struct {
+	Nodes    []swarm.Node
+	Services []swarm.Service
+	Tasks    []swarm.Task
+}
+
+type SwarmNodeSummary struct {
+	Id           string
+	Hostname     string
+	Role         string
+	Leader       bool `json:",omitempty"`
+	State        string
+	Availability string
+	Tasks        []SwarmTaskSummary
+}
+
+type SwarmTaskSummary struct {
+	Id           string
+	Name         string
+	Service      string
+	NodeId       string `json:",omitempty"`
+	DesiredState string
+	CurrentState string
+	Error        string                `json:",omitempty"`
+	Networks     []SwarmTaskAttachment `json:",omitempty"`
+}
+
+type SwarmTaskAttachment struct {
+	Network   string
+	Addresses []string `json:",omitempty"`
+}
+
+type SwarmServiceSummary struct {
+	Id       string
+	Name     string
+	Mode     string
+	Desired  int
+	Running  int
+	Ports    []string `json:",omitempty"`
+	Networks []string `json:",omitempty"`
+}
+
+type SwarmSummary struct {
+	Nodes    []SwarmNodeSummary
+	Services []SwarmServiceSummary
+}
+
+type SwarmCommand struct {
+	Dot        bool `short:"d" long:"dot" description:"Show swarm nodes, tasks and services as Graphviz dot."`
+	Tree       bool `short:"t" long:"tree" description:"Show swarm nodes and their tasks as a tree."`
+	JSON       bool `short:"j" long:"json" description:"Show swarm nodes, tasks and services as JSON."`
+	All        bool `short:"a" long:"all" description:"Include tasks that have been shut down, not just the current ones."`
+	NoTruncate bool `short:"n" long:"no-trunc" description:"Don't truncate the task and node IDs."`
+}
+
+var swarmCommand SwarmCommand
+
+// a task with no node yet is hung off this stand-in node
+const unassignedNode = "unassigned"
+
+func (x *SwarmCommand) Execute(args []string) error {
+
+	var cluster *Swarm
+
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading stdin stat: %s", err)
+	}
+
+	if globalOptions.Stdin && (stat.Mode()&os.ModeCharDevice) == 0 {
+		// read in stdin
+		stdin, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("error reading all input: %s", err)
+		}
+
+		cluster, err = parseSwarmJSON(stdin)
+		if err != nil {
+			return err
+		}
+	} else {
+
+		client, err := connect()
+		if err != nil {
+			return err
+		}
+
+		cluster, err = listSwarm(client)
+		if err != nil {
+			return fmt.Errorf("Unable to read swarm state, is this a swarm manager?: %s\nFor help, run 'dockviz help'", err)
+		}
+	}
+
+	summary := summarizeSwarm(cluster, swarmCommand.All)
+
+	if swarmCommand.Dot {
+		fmt.Print(jsonSwarmToDot(summary, swarmCommand.NoTruncate))
+	} else if swarmCommand.Tree {
+		fmt.Print(jsonSwarmToTree(summary, swarmCommand.NoTruncate))
+	} else if swarmCommand.JSON {
+		result, err := jsonSwarmToJSON(summary)
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+	} else {
+		return fmt.Errorf("Please specify either --dot, --tree, or --json")
+	}
+
+	return nil
+}
+
+func listSwarm(client *docker.Client) (*Swarm, error) {
+	var cluster Swarm
+	var err error
+
+	if cluster.Nodes, err = client.ListNodes(docker.ListNodesOptions{}); err != nil {
+		return nil, err
+	}
+	if cluster.Services, err = client.ListServices(docker.ListServicesOptions{}); err != nil {
+		return nil, err
+	}
+	if cluster.Tasks, err = client.ListTasks(docker.ListTasksOptions{}); err != nil {
+		return nil, err
+	}
+
+	return &cluster, nil
+}
+
+func parseSwarmJSON(rawJSON []byte) (*Swarm, error) {
+
+	var cluster Swarm
+
+	err := json.Unmarshal(rawJSON, &cluster)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading JSON: %s", err)
+	}
+
+	return &cluster, nil
+}
+
+// summarizeSwarm puts each task under the node it was scheduled on and
+// counts the replicas of each service.  Tasks the orchestrator has already
+// told to shut down are left out unless All is set.
+func summarizeSwarm(cluster *Swarm, All bool) SwarmSummary {
+	var summary SwarmSummary
+
+	services := make(map[string]*SwarmServiceSummary)
+	for _, service := range cluster.Services {
+		summary.Services = append(summary.Services, SwarmServiceSummary{
+			Id:    service.ID,
+			Name:  service.Spec.Name,
+			Mode:  swarmServiceMode(service),
+			Ports: swarmServicePorts(service),
+		})
+	}
+	sort.Slice(summary.Services, func(a, b int) bool {
+		return summary.Services[a].Name < summary.Services[b].Name
+	})
+	for i := range summary.Services {
+		services[summary.Services[i].Id] = &summary.Services[i]
+	}
+
+	desired := make(map[string]int)
+	for _, service := range cluster.Services {
+		if service.Spec.Mode.Replicated != nil && service.Spec.Mode.Replicated.Replicas != nil {
+			desired[service.ID] = int(*service.Spec.Mode.Replicated.Replicas)
+		} else {
+			desired[service.ID] = -1
+		}
+	}
+
+	tasks := make(map[string][]SwarmTaskSummary)
+	serviceNetworks := make(map[string]map[string]bool)
+	for _, task := range cluster.Tasks {
+		current := task.DesiredState != swarm.TaskStateShutdown && task.DesiredState != swarm.TaskStateRemove
+
+		service := services[task.ServiceID]
+		if service != nil && current {
+			if desired[task.ServiceID] < 0 {
+				// global services want one task on each eligible node
+				service.Desired++
+			}
+			if task.Status.State == swarm.TaskStateRunning {
+				service.Running++
+			}
+		}
+
+		if !current && !All {
+			continue
+		}
+
+		summaryTask := SwarmTaskSummary{
+			Id:           task.ID,
+			Name:         swarmTaskName(task, service),
+			NodeId:       task.NodeID,
+			DesiredState: string(task.DesiredState),
+			CurrentState: string(task.Status.State),
+			Error:        task.Status.Err,
+		}
+		if service != nil {
+			summaryTask.Service = service.Name
+		}
+
+		for _, attachment := range task.NetworksAttachments {
+			// the routing mesh is shown by the published ports instead
+			if attachment.Network.Spec.Ingress {
+				continue
+			}
+			name := attachment.Network.Spec.Name
+			summaryTask.Networks = append(summaryTask.Networks, SwarmTaskAttachment{Network: name, Addresses: attachment.Addresses})
+			if serviceNetworks[task.ServiceID] == nil {
+				serviceNetworks[task.ServiceID] = make(map[string]bool)
+			}
+			serviceNetworks[task.ServiceID][name] = true
+		}
+
+		node := task.NodeID
+		if node == "" {
+			node = unassignedNode
+		}
+		tasks[node] = append(tasks[node], summaryTask)
+	}
+
+	for i := range summary.Services {
+		service := &summary.Services[i]
+		if desired[service.Id] >= 0 {
+			service.Desired = desired[service.Id]
+		}
+		for network := range serviceNetworks[service.Id] {
+			service.Networks = append(service.Networks, network)
+		}
+		sort.Strings(service.Networks)
+	}
+
+	for _, node := range cluster.Nodes {
+		summaryNode := SwarmNodeSummary{
+			Id:           node.ID,
+			Hostname:     node.Description.Hostname,
+			Role:         string(node.Spec.Role),
+			State:        string(node.Status.State),
+			Availability: string(node.Spec.Availability),
+			Tasks:        tasks[node.ID],
+		}
+		if node.ManagerStatus != nil {
+			summaryNode.Leader = node.ManagerStatus.Leader
+		}
+		summary.Nodes = append(summary.Nodes, summaryNode)
+	}
+	sort.Slice(summary.Nodes, func(a, b int) bool {
+		return summary.Nodes[a].Hostname < summary.Nodes[b].Hostname
+	})
+	if len(tasks[unassignedNode]) > 0 {
+		summary.Nodes = append(summary.Nodes, SwarmNodeSummary{Id: unassignedNode, Hostname: "(unassigned)", Tasks: tasks[unassignedNode]})
+	}
+
+	for i := range summary.Nodes {
+		nodeTasks := summary.Nodes[i].Tasks
+		sort.SliceStable(nodeTasks, func(a, b int) bool {
+			return nodeTasks[a].Name < nodeTasks[b].Name
+		})
+	}
+
+	return summary
+}
+
+func swarmServiceMode(service swarm.Service) string {
+	switch {
+	case service.Spec.Mode.Global != nil:
+		return "global"
+	case service.Spec.Mode.ReplicatedJob != nil:
+		return "replicated-job"
+	case service.Spec.Mode.GlobalJob != nil:
+		return "global-job"
+	}
+	return "replicated"
+}
+
+// swarmServicePorts lists published ports like docker service ls does, with
+// ports published on the host rather than the routing mesh marked as such.
+func swarmServicePorts(service swarm.Service) []string {
+	ports := service.Endpoint.Ports
+	if len(ports) == 0 && service.Spec.EndpointSpec != nil {
+		ports = service.Spec.EndpointSpec.Ports
+	}
+
+	var result []string
+	for _, port := range ports {
+		if port.PublishedPort == 0 {
+			continue
+		}
+		text := fmt.Sprintf("%d->%d/%s", port.PublishedPort, port.TargetPort, port.Protocol)
+		if port.PublishMode == swarm.PortConfigPublishModeHost {
+			text = "host:" + text
+		}
+		result = append(result, text)
+	}
+	return result
+}
+
+// swarmTaskName names tasks the way docker service ps does: by slot for
+// replicated services and by node for global ones.
+func swarmTaskName(task swarm.Task, service *SwarmServiceSummary) string {
+	name := task.ServiceID
+	if service != nil {
+		name = service.Name
+	}
+	if task.Slot > 0 {
+		return fmt.Sprintf("%s.%d", name, task.Slot)
+	}
+	return fmt.Sprintf("%s.%s", name, truncate(task.NodeID, 12))
+}
+
+func (s SwarmServiceSummary) labelParts() []string {
+	parts := []string{s.Name, fmt.Sprintf("%s %d/%d", s.Mode, s.Running, s.Desired)}
+	if len(s.Ports) > 0 {
+		parts = append(parts, strings.Join(s.Ports, ", "))
+	}
+	return parts
+}
+
+func (t SwarmTaskSummary) stateLabel() string {
+	label := fmt.Sprintf("%s (desired %s)", t.CurrentState, t.DesiredState)
+	if t.Error != "" {
+		label = label + " " + t.Error
+	}
+	return label
+}
+
+// dotEscape makes text safe inside a quoted dot ID or label.  Task errors
+// in particular come from the daemon and often quote what went wrong.
+func dotEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+}
+
+// swarmTaskColor tells apart tasks that are where the orchestrator wants
+// them, ones still converging and ones that have failed.
+func swarmTaskColor(task SwarmTaskSummary) string {
+	switch {
+	case task.CurrentState == string(swarm.TaskStateFailed) || task.CurrentState == string(swarm.TaskStateRejected):
+		return "tomato"
+	case task.CurrentState == task.DesiredState:
+		if task.CurrentState == string(swarm.TaskStateRunning) {
+			return "paleturquoise"
+		}
+		return "lightgrey"
+	}
+	return "orange"
+}
+
+func jsonSwarmToDot(summary SwarmSummary, NoTruncate bool) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph swarm {\n")
+	buffer.WriteString(" rankdir=LR;\n")
+
+	for _, node := range summary.Nodes {
+		label := []string{node.Hostname}
+		if node.Id != unassignedNode {
+			role := node.Role
+			if node.Leader {
+				role = role + " (leader)"
+			}
+			label = append(label, role, node.State+" "+node.Availability)
+		}
+		for i := range label {
+			label[i] = dotEscape(label[i])
+		}
+		buffer.WriteString(fmt.Sprintf(" \"node:%s\" [label=\"%s\",shape=box3d];\n", node.Id, strings.Join(label, "\\n")))
+
+		for _, task := range node.Tasks {
+			taskID := task.Id
+			if !NoTruncate {
+				taskID = truncate(taskID, 12)
+			}
+			buffer.WriteString(fmt.Sprintf(" \"task:%s\" [label=\"%s\\n%s\\n%s\",shape=box,fillcolor=\"%s\",style=\"filled,rounded\"];\n", task.Id, dotEscape(task.Name), taskID, dotEscape(task.stateLabel()), swarmTaskColor(task)))
+			buffer.WriteString(fmt.Sprintf(" \"node:%s\" -> \"task:%s\"\n", node.Id, task.Id))
+			if task.Service != "" {
+				buffer.WriteString(fmt.Sprintf(" \"task:%s\" -> \"service:%s\"\n", task.Id, dotEscape(task.Service)))
+			}
+		}
+	}
+
+	var networks []string
+	seen := make(map[string]bool)
+	for _, service := range summary.Services {
+		fill := "paleturquoise"
+		if service.Running < service.Desired {
+			fill = "orange"
+		}
+		label := service.labelParts()
+		for i := range label {
+			label[i] = dotEscape(label[i])
+		}
+		buffer.WriteString(fmt.Sprintf(" \"service:%s\" [label=\"%s\",shape=component,fillcolor=\"%s\",style=\"filled\"];\n", dotEscape(service.Name), strings.Join(label, "\\n"), fill))
+		for _, network := range service.Networks {
+			if !seen[network] {
+				seen[network] = true
+				networks = append(networks, network)
+			}
+			buffer.WriteString(fmt.Sprintf(" \"service:%s\" -> \"network:%s\" [dir=none,style=dashed]\n", dotEscape(service.Name), dotEscape(network)))
+		}
+	}
+
+	sort.Strings(networks)
+	for _, network := range networks {
+		buffer.WriteString(fmt.Sprintf(" \"network:%s\" [label=\"%s\",shape=ellipse,fillcolor=\"khaki\",style=\"filled\"];\n", dotEscape(network), dotEscape(network)))
+	}
+
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+// jsonSwarmToTree lists each node with the tasks scheduled on it, and under
+// each task the service it belongs to and the overlay networks it is on.
+func jsonSwarmToTree(summary SwarmSummary, NoTruncate bool) string {
+	var buffer bytes.Buffer
+
+	services := make(map[string]SwarmServiceSummary)
+	for _, service := range summary.Services {
+		services[service.Name] = service
+	}
+
+	var nodes []TextNode
+	for _, node := range summary.Nodes {
+		line := node.Hostname
+		if node.Id != unassignedNode {
+			role := node.Role
+			if node.Leader {
+				role = role + ", leader"
+			}
+			line = fmt.Sprintf("%s (%s) %s %s", node.Hostname, role, node.State, node.Availability)
+		}
+		nodeNode := TextNode{Line: line}
+
+		for _, task := range node.Tasks {
+			taskID := task.Id
+			if !NoTruncate {
+				taskID = truncate(taskID, 12)
+			}
+			taskNode := TextNode{Line: fmt.Sprintf("%s %s %s", task.Name, taskID, task.stateLabel())}
+			if service, ok := services[task.Service]; ok {
+				line := fmt.Sprintf("service %s %s %d/%d", service.Name, service.Mode, service.Running, service.Desired)
+				if len(service.Ports) > 0 {
+					line = line + " Ports: " + strings.Join(service.Ports, ", ")
+				}
+				taskNode.Children = append(taskNode.Children, TextNode{Line: line})
+			}
+			for _, attachment := range task.Networks {
+				taskNode.Children = append(taskNode.Children, TextNode{Line: strings.Join(append([]string{"network " + attachment.Network}, attachment.Addresses...), " ")})
+			}
+			nodeNode.Children = append(nodeNode.Children, taskNode)
+		}
+		nodes = append(nodes, nodeNode)
+	}
+
+	writeTextTree(&buffer, nodes, "")
+
+	return buffer.String()
+}
+
+func jsonSwarmToJSON(summary SwarmSummary) (string, error) {
+	result, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Error writing JSON: %s", err)
+	}
+
+	return string(result) + "\n", nil
+}
+
+func init() {
+	parser.AddCommand("swarm",
+		"Visualize docker swarm nodes, tasks and services.",
+		"",
+		&swarmCommand)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const swarmNodesJSON = `[{"ID":"n1manager0000000000000000","Spec":{"Role":"manager","Availability":"active"},"Description":{"Hostname":"manager1"},"Status":{"State":"ready","Addr":"10.0.0.1"},"ManagerStatus":{"Leader":true,"Reachability":"reachable","Addr":"10.0.0.1:2377"}},{"ID":"n2worker00000000000000000","Spec":{"Role":"worker","Availability":"drain"},"Description":{"Hostname":"worker1"},"Status":{"State":"ready","Addr":"10.0.0.2"}}]`
+
+const swarmServicesJSON = `[{"ID":"s1web000000000000000000000","Spec":{"Name":"web","Mode":{"Replicated":{"Replicas":3}}},"Endpoint":{"Ports":[{"Protocol":"tcp","TargetPort":80,"PublishedPort":8080,"PublishMode":"ingress"}]}},{"ID":"s2agent0000000000000000000","Spec":{"Name":"agent","Mode":{"Global":{}}},"Endpoint":{"Ports":[{"Protocol":"udp","TargetPort":8125,"PublishedPort":8125,"PublishMode":"host"}]}}]`
+
+const swarmTasksJSON = `[` +
+	`{"ID":"t1web1aaaaaaaaaaaaaaaaaaaa","ServiceID":"s1web000000000000000000000","Slot":1,"NodeID":"n1manager0000000000000000","Status":{"State":"running"},"DesiredState":"running","NetworksAttachments":[{"Network":{"Spec":{"Name":"ingress","Ingress":true}},"Addresses":["10.255.0.5/16"]},{"Network":{"Spec":{"Name":"frontend"}},"Addresses":["10.0.1.3/24"]}]},` +
+	`{"ID":"t2web2bbbbbbbbbbbbbbbbbbbb","ServiceID":"s1web000000000000000000000","Slot":2,"NodeID":"n2worker00000000000000000","Status":{"State":"failed","Err":"task: non-zero exit (1)"},"DesiredState":"shutdown"},` +
+	`{"ID":"t3web2cccccccccccccccccccc","ServiceID":"s1web000000000000000000000","Slot":2,"NodeID":"n1manager0000000000000000","Status":{"State":"starting"},"DesiredState":"running","NetworksAttachments":[{"Network":{"Spec":{"Name":"frontend"}},"Addresses":["10.0.1.4/24"]}]},` +
+	`{"ID":"t4web3dddddddddddddddddddd","ServiceID":"s1web000000000000000000000","Slot":3,"Status":{"State":"pending","Err":"no suitable node"},"DesiredState":"running"},` +
+	`{"ID":"t5agenteeeeeeeeeeeeeeeeeee","ServiceID":"s2agent0000000000000000000","NodeID":"n1manager0000000000000000","Status":{"State":"running"},"DesiredState":"running"}` +
+	`]`
+
+const swarmJSON = `{"Nodes":` + swarmNodesJSON + `,"Services":` + swarmServicesJSON + `,"Tasks":` + swarmTasksJSON + `}`
+
+func Test_SwarmDot(t *testing.T) {
+	cluster, err := parseSwarmJSON([]byte(swarmJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := jsonSwarmToDot(summarizeSwarm(cluster, false), false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "node:n1manager0000000000000000" \[label="manager1\\nmanager \(leader\)\\nready active",shape=box3d\];$`,
+		`(?m)^ "node:unassigned" \[label="\(unassigned\)",shape=box3d\];$`,
+		`(?m)^ "task:t1web1aaaaaaaaaaaaaaaaaaaa" \[label="web.1\\nt1web1aaaaaa\\nrunning \(desired running\)",shape=box,fillcolor="paleturquoise",`,
+		`(?m)^ "task:t3web2cccccccccccccccccccc" \[label="web.2\\nt3web2cccccc\\nstarting \(desired running\)",shape=box,fillcolor="orange",`,
+		`(?m)^ "task:t4web3dddddddddddddddddddd" \[label="web.3\\nt4web3dddddd\\npending \(desired running\) no suitable node",`,
+		`(?m)^ "task:t5agenteeeeeeeeeeeeeeeeeee" \[label="agent.n1manager000\\n`,
+		`(?m)^ "node:n1manager0000000000000000" -> "task:t1web1aaaaaaaaaaaaaaaaaaaa"$`,
+		`(?m)^ "node:unassigned" -> "task:t4web3dddddddddddddddddddd"$`,
+		`(?m)^ "task:t1web1aaaaaaaaaaaaaaaaaaaa" -> "service:web"$`,
+		`(?m)^ "service:web" \[label="web\\nreplicated 1/3\\n8080->80/tcp",shape=component,fillcolor="orange",`,
+		`(?m)^ "service:agent" \[label="agent\\nglobal 1/1\\nhost:8125->8125/udp",shape=component,fillcolor="paleturquoise",`,
+		`(?m)^ "service:web" -> "network:frontend" \[dir=none,style=dashed\]$`,
+		`(?m)^ "network:frontend" \[label="frontend",shape=ellipse,`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("swarm dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	for _, excluded := range []string{"t2web2", "network:ingress"} {
+		if strings.Contains(result, excluded) {
+			t.Fatalf("swarm dot content '%s' should not contain '%s'", result, excluded)
+		}
+	}
+
+	result = jsonSwarmToDot(summarizeSwarm(cluster, true), false)
+	if !strings.Contains(result, ` "task:t2web2bbbbbbbbbbbbbbbbbbbb" [label="web.2\nt2web2bbbbbb\nfailed (desired shutdown) task: non-zero exit (1)",shape=box,fillcolor="tomato",`) {
+		t.Fatalf("swarm dot content '%s' did not include the shut down task", result)
+	}
+}
+
+func Test_SwarmDotEscaping(t *testing.T) {
+	escapingJSON := `{"Nodes":[],"Services":[{"ID":"s1web000000000000000000000","Spec":{"Name":"we\\b\"1","Mode":{"Replicated":{"Replicas":1}}}}],"Tasks":[` +
+		`{"ID":"t1web1aaaaaaaaaaaaaaaaaaaa","ServiceID":"s1web000000000000000000000","Slot":1,"Status":{"State":"rejected","Err":"invalid mount config for type \"bind\": bind source path does not exist: C:\\data"},"DesiredState":"running"}]}`
+	cluster, err := parseSwarmJSON([]byte(escapingJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := jsonSwarmToDot(summarizeSwarm(cluster, false), false)
+	for _, expected := range []string{
+		`[label="we\\b\"1.1\nt1web1aaaaaa\nrejected (desired running) invalid mount config for type \"bind\": bind source path does not exist: C:\\data",`,
+		` "task:t1web1aaaaaaaaaaaaaaaaaaaa" -> "service:we\\b\"1"`,
+		` "service:we\\b\"1" [label="we\\b\"1\nreplicated 0/1",`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Fatalf("swarm dot content '%s' did not contain '%s'", result, expected)
+		}
+	}
+}
+
+func Test_SwarmTree(t *testing.T) {
+	cluster, _ := parseSwarmJSON([]byte(swarmJSON))
+
+	result := jsonSwarmToTree(summarizeSwarm(cluster, false), false)
+	expected := "├─manager1 (manager, leader) ready active\n" +
+		"│ ├─agent.n1manager000 t5agenteeeee running (desired running)\n" +
+		"│ │ └─service agent global 1/1 Ports: host:8125->8125/udp\n" +
+		"│ ├─web.1 t1web1aaaaaa running (desired running)\n" +
+		"│ │ ├─service web replicated 1/3 Ports: 8080->80/tcp\n" +
+		"│ │ └─network frontend 10.0.1.3/24\n" +
+		"│ └─web.2 t3web2cccccc starting (desired running)\n" +
+		"│   ├─service web replicated 1/3 Ports: 8080->80/tcp\n" +
+		"│   └─network frontend 10.0.1.4/24\n" +
+		"├─worker1 (worker) ready drain\n" +
+		"└─(unassigned)\n" +
+		"  └─web.3 t4web3dddddd pending (desired running) no suitable node\n" +
+		"    └─service web replicated 1/3 Ports: 8080->80/tcp\n"
+	if result != expected {
+		t.Fatalf("swarm tree '%s' did not match '%s'", result, expected)
+	}
+}
+
+// Test_SwarmDaemon reads the recorded responses through the client from a
+// stand-in daemon, the same way dockviz does against a real one.
+func Test_SwarmDaemon(t *testing.T) {
+	responses := map[string]string{
+		"/nodes":    swarmNodesJSON,
+		"/services": swarmServicesJSON,
+		"/tasks":    swarmTasksJSON,
+	}
+	client := newStandInDaemon(t, responses, nil)
+	cluster, err := listSwarm(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := jsonSwarmToJSON(summarizeSwarm(cluster, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, regexp := range compileRegexps(t, []string{
+		`"Hostname": "manager1",\s+"Role": "manager",\s+"Leader": true,`,
+		`"Name": "web",\s+"Mode": "replicated",\s+"Desired": 3,\s+"Running": 1,\s+"Ports": \[\s+"8080-\\u003e80/tcp"\s+\],\s+"Networks": \[\s+"frontend"\s+\]`,
+		`"Name": "web.3",\s+"Service": "web",\s+"DesiredState": "running",\s+"CurrentState": "pending",\s+"Error": "no suitable node"`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("swarm json content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
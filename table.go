@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type imageRow struct {
+	Image      Image
+	Depth      int
+	ChildCount int
+}
+
+var imageColumns = []string{"id", "parent_id", "depth", "tags", "size", "virtual_size", "created", "children", "created_by"}
+
+var containerColumns = []string{"id", "name", "image", "status", "created", "ports", "links", "command", "size_rw", "size_root_fs", "risks"}
+
+// jsonToTable writes one row per layer, walking the tree depth first with
+// siblings sorted by creation time so the output is stable between runs.
+// Parents are written with their original ID, the same as the id column,
+// since newer daemons only give synthesized IDs to link layers by.
+func jsonToTable(roots []Image, byParent map[string][]Image, dispOpts DisplayOpts, columns []string, comma rune) (string, error) {
+	var rows []imageRow
+	origIds := make(map[string]string)
+	var walk func(images []Image, depth int)
+	walk = func(images []Image, depth int) {
+		for _, image := range sortedImages(images) {
+			rows = append(rows, imageRow{image, depth, len(byParent[image.Id])})
+			origIds[image.Id] = image.OrigId
+			walk(byParent[image.Id], depth+1)
+		}
+	}
+	walk(roots, 0)
+
+	id := func(id string) string {
+		if dispOpts.NoTruncate {
+			return id
+		}
+		return truncate(stripPrefix(id), 12)
+	}
+
+	var records [][]string
+	for _, row := range rows {
+		var record []string
+		for _, column := range columns {
+			var value string
+			switch column {
+			case "id":
+				value = id(row.Image.OrigId)
+			case "parent_id":
+				if parent, ok := origIds[row.Image.ParentId]; ok {
+					value = id(parent)
+				} else {
+					value = id(row.Image.ParentId)
+				}
+			case "depth":
+				value = strconv.Itoa(row.Depth)
+			case "tags":
+				if row.Image.RepoTags[0] != "<none>:<none>" {
+					value = strings.Join(row.Image.RepoTags, " ")
+				}
+			case "size":
+				value = strconv.FormatInt(row.Image.Size, 10)
+			case "virtual_size":
+				value = strconv.FormatInt(row.Image.VirtualSize, 10)
+			case "created":
+				value = time.Unix(row.Image.Created, 0).UTC().Format(time.RFC3339)
+			case "children":
+				value = strconv.Itoa(row.ChildCount)
+			case "created_by":
+				value = SanitizeCommand(row.Image.CreatedBy, len(row.Image.CreatedBy))
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+
+	return writeTable(columns, records, comma)
+}
+
+func jsonContainersToTable(containers *[]Container, OnlyRunning bool, columns []string, comma rune) (string, error) {
+	links := make(map[string][]string)
+	for _, link := range collectContainerLinks(containers, OnlyRunning) {
+		links[link.Target] = append(links[link.Target], fmt.Sprintf("%s:%s", link.Source, link.Alias))
+	}
+
+	var records [][]string
+	for _, container := range sortedContainers(containers, OnlyRunning) {
+		var record []string
+		for _, column := range columns {
+			var value string
+			switch column {
+			case "id":
+				value = truncate(container.Id, 12)
+			case "name":
+				value = primaryContainerName(container)
+			case "image":
+				value = container.Image
+			case "status":
+				value = container.Status
+			case "created":
+				value = time.Unix(container.Created, 0).UTC().Format(time.RFC3339)
+			case "ports":
+				value = formatPorts(container.Ports)
+			case "links":
+				value = strings.Join(links[primaryContainerName(container)], " ")
+			case "command":
+				value = container.Command
+			case "size_rw":
+				value = strconv.FormatInt(container.SizeRw, 10)
+			case "size_root_fs":
+				value = strconv.FormatInt(container.SizeRootFs, 10)
+			case "risks":
+				value = containerRiskList(container)
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+
+	return writeTable(columns, records, comma)
+}
+
+func writeTable(columns []string, records [][]string, comma rune) (string, error) {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+	writer.Comma = comma
+	writer.Write(columns)
+	writer.WriteAll(records)
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("Error writing table: %s", err)
+	}
+
+	return buffer.String(), nil
+}
+
+// parseColumns checks a comma separated list of column names against the
+// columns available, defaulting to all of them.
+func parseColumns(spec string, available []string) ([]string, error) {
+	if spec == "" {
+		return available, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(spec, ",") {
+		column = strings.TrimSpace(column)
+		found := false
+		for _, name := range available {
+			if name == column {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown column '%s', choose from: %s", column, strings.Join(available, ", "))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func sortedImages(images []Image) []Image {
+	sorted := append([]Image{}, images...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if sorted[a].Created != sorted[b].Created {
+			return sorted[a].Created < sorted[b].Created
+		}
+		return sorted[a].Id < sorted[b].Id
+	})
+	return sorted
+}
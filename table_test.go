@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_Table(t *testing.T) {
+	tableJSON := `[{"VirtualSize":674553464,"Size":2000000,"RepoTags":["foo:latest","foo:1.0"],"ParentId":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Id":"c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","Created":1386142125},{"VirtualSize":682553464,"Size":20000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"626147582d2ae3735f5db5f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142124,"CreatedBy":"/bin/sh -c echo \"a, b\""},{"VirtualSize":672553464,"Size":10000000,"RepoTags":["<none>:<none>"],"ParentId":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142123},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Created":1386114144}]`
+
+	im, _ := parseImagesJSON([]byte(tableJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+
+	result, err := jsonToTable(collectRoots(im), collectChildren(im), DisplayOpts{}, imageColumns, ',')
+	if err != nil {
+		t.Fatalf("images table failed: %s", err)
+	}
+
+	expected := "id,parent_id,depth,tags,size,virtual_size,created,children,created_by\n" +
+		"4c1208b690c6,,0,,662553464,662553464,2013-12-03T23:42:24Z,2,\n" +
+		"735f5db56261,4c1208b690c6,1,,10000000,672553464,2013-12-04T07:28:43Z,1,\n" +
+		"c87be8e5e697,735f5db56261,2,foo:latest foo:1.0,2000000,674553464,2013-12-04T07:28:45Z,0,\n" +
+		"626147582d2a,4c1208b690c6,1,,20000000,682553464,2013-12-04T07:28:44Z,0,\"echo a, b\"\n"
+	if result != expected {
+		t.Fatalf("images table '%s' did not match '%s'", result, expected)
+	}
+
+	columns, _ := parseColumns("depth, id", imageColumns)
+	result, _ = jsonToTable(collectRoots(im), collectChildren(im), DisplayOpts{}, columns, '\t')
+	expected = "depth\tid\n0\t4c1208b690c6\n1\t735f5db56261\n2\tc87be8e5e697\n1\t626147582d2a\n"
+	if result != expected {
+		t.Fatalf("images tsv '%s' did not match '%s'", result, expected)
+	}
+}
+
+// Test_TableSynthesized checks that parents are written with the same IDs as
+// the id column when the layers were synthesized from the image history.
+func Test_TableSynthesized(t *testing.T) {
+	synthJSON := `[{"VirtualSize":674553464,"Size":12000000,"RepoTags":["foo:latest"],"ParentId":"synth:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","Id":"synth:c87be8e5e697c735f5db5626147582d2ae3f2088574c5faaf8d4d1bccab99470","OrigId":"sha256:735f5db5626147582d2ae3f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386142125},{"VirtualSize":662553464,"Size":662553464,"RepoTags":["<none>:<none>"],"ParentId":"","Id":"synth:4c1208b690c68af3476b437e7bc2bcc460f062bda2094d2d8f21a7e70368d358","OrigId":"sha256:626147582d2ae3735f5db5f2c87be8e5e697c088574c5faaf8d4d1bccab99470","Created":1386114144}]`
+
+	im, _ := parseImagesJSON([]byte(synthJSON))
+
+	columns, _ := parseColumns("id,parent_id", imageColumns)
+	result, err := jsonToTable(collectRoots(im), collectChildren(im), DisplayOpts{}, columns, ',')
+	if err != nil {
+		t.Fatalf("images table failed: %s", err)
+	}
+
+	expected := "id,parent_id\n" +
+		"626147582d2a,\n" +
+		"735f5db56261,626147582d2a\n"
+	if result != expected {
+		t.Fatalf("images table '%s' did not match '%s'", result, expected)
+	}
+}
+
+func Test_ParseColumns(t *testing.T) {
+	if _, err := parseColumns("id,bogus", imageColumns); err == nil {
+		t.Error("unknown column did not cause an error")
+	}
+
+	columns, err := parseColumns("", containerColumns)
+	if err != nil || len(columns) != len(containerColumns) {
+		t.Errorf("empty column list did not default to all columns: %v", columns)
+	}
+}
+
+func Test_ContainersTable(t *testing.T) {
+	containers, _ := parseContainersJSON([]byte(containersJSON))
+
+	columns, _ := parseColumns("name,status,ports,links", containerColumns)
+	result, err := jsonContainersToTable(containers, true, columns, ',')
+	if err != nil {
+		t.Fatalf("containers table failed: %s", err)
+	}
+
+	expected := "name,status,ports,links\n" +
+		"app1,Up 2 minutes,,redis:db\n" +
+		"redis,Up 4 minutes,6379/tcp,\n"
+	if result != expected {
+		t.Fatalf("containers table '%s' did not match '%s'", result, expected)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HostTop is what is running in each container on a host, which is also
+// the shape read from stdin.
+type HostTop struct {
+	Host       string
+	Containers []ContainerTop
+}
+
+// ContainerTop is a container's process list as returned by the daemon's
+// top endpoint, with a title for each column.
+type ContainerTop struct {
+	Id        string
+	Name      string
+	Image     string
+	Titles    []string
+	Processes [][]string
+}
+
+type TopProcess struct {
+	PID     int
+	PPID    int
+	User    string
+	Command string
+}
+
+type TopCommand struct {
+	Dot        bool `short:"d" long:"dot" description:"Show the processes as Graphviz dot."`
+	NoTruncate bool `short:"n" long:"no-trunc" description:"Don't truncate the container IDs or commands."`
+}
+
+var topCommand TopCommand
+
+func (x *TopCommand) Execute(args []string) error {
+
+	var top *HostTop
+
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading stdin stat: %s", err)
+	}
+
+	if globalOptions.Stdin && (stat.Mode()&os.ModeCharDevice) == 0 {
+		// read in stdin
+		stdin, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("error reading all input: %s", err)
+		}
+
+		top, err = parseTopJSON(stdin)
+		if err != nil {
+			return err
+		}
+	} else {
+
+		client, err := connect()
+		if err != nil {
+			return err
+		}
+
+		top, err = listTop(client)
+		if err != nil {
+			return fmt.Errorf("Unable to connect: %s\nFor help, run 'dockviz help'", err)
+		}
+	}
+
+	if topCommand.Dot {
+		fmt.Print(jsonTopToDot(top, topCommand.NoTruncate))
+	} else {
+		fmt.Print(jsonTopToTree(top, topCommand.NoTruncate))
+	}
+
+	return nil
+}
+
+// listTop asks every running container for its processes concurrently.
+// Containers that stop or are removed while being asked are skipped.
+func listTop(client *docker.Client) (*HostTop, error) {
+	var top HostTop
+
+	info, err := client.Info()
+	if err != nil {
+		return nil, err
+	}
+	top.Host = info.Name
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// gathered by index so the goroutines don't share the list
+	tops := make([]*ContainerTop, len(containers))
+	err = forEachConcurrently(len(containers), func(i int) error {
+		container := containers[i]
+		result, err := client.TopContainer(container.ID, "")
+		if err != nil {
+			if stoppedContainer(err) {
+				return nil
+			}
+			return fmt.Errorf("Error listing processes of %s: %s", container.ID, err)
+		}
+
+		name := container.ID
+		for _, containerName := range container.Names {
+			if strings.Count(containerName, "/") == 1 {
+				name = containerName[1:]
+			}
+		}
+		tops[i] = &ContainerTop{container.ID, name, container.Image, result.Titles, result.Processes}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, containerTop := range tops {
+		if containerTop != nil {
+			top.Containers = append(top.Containers, *containerTop)
+		}
+	}
+	return &top, nil
+}
+
+func parseTopJSON(rawJSON []byte) (*HostTop, error) {
+
+	var top HostTop
+
+	err := json.Unmarshal(rawJSON, &top)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading JSON: %s", err)
+	}
+
+	return &top, nil
+}
+
+// processes reads the process list using its titles, which depend on the
+// ps arguments and platform: ps -ef gives UID, PID, PPID and CMD, other
+// arguments give USER and COMMAND, and Windows gives no parent at all.
+func (c ContainerTop) processes() []TopProcess {
+	columns := make(map[string]int)
+	for index, title := range c.Titles {
+		columns[strings.ToUpper(title)] = index
+	}
+	field := func(process []string, titles ...string) string {
+		for _, title := range titles {
+			if index, ok := columns[title]; ok && index < len(process) {
+				return process[index]
+			}
+		}
+		return ""
+	}
+
+	var processes []TopProcess
+	for _, process := range c.Processes {
+		pid, _ := strconv.Atoi(field(process, "PID"))
+		ppid, _ := strconv.Atoi(field(process, "PPID"))
+		processes = append(processes, TopProcess{
+			PID:     pid,
+			PPID:    ppid,
+			User:    field(process, "UID", "USER"),
+			Command: field(process, "CMD", "COMMAND", "NAME"),
+		})
+	}
+	return processes
+}
+
+// processChildren groups processes under their parent, with the ones whose
+// parent is outside the container under 0, each sorted by PID.  Processes
+// without a PID can't have children, so are only ever leaves.
+func processChildren(processes []TopProcess) map[int][]TopProcess {
+	inside := make(map[int]bool)
+	for _, process := range processes {
+		inside[process.PID] = true
+	}
+
+	children := make(map[int][]TopProcess)
+	for _, process := range processes {
+		parent := process.PPID
+		if !inside[parent] || parent == process.PID {
+			parent = 0
+		}
+		children[parent] = append(children[parent], process)
+	}
+	for _, list := range children {
+		sort.SliceStable(list, func(a, b int) bool {
+			return list[a].PID < list[b].PID
+		})
+	}
+	return children
+}
+
+func sortedTopContainers(top *HostTop) []ContainerTop {
+	containers := append([]ContainerTop{}, top.Containers...)
+	sort.Slice(containers, func(a, b int) bool {
+		return containers[a].Name < containers[b].Name
+	})
+	return containers
+}
+
+func (p TopProcess) command(NoTruncate bool) string {
+	if NoTruncate {
+		return p.Command
+	}
+	return truncate(p.Command, 60)
+}
+
+// jsonTopToTree draws the host, its running containers and the process
+// tree inside each one.
+func jsonTopToTree(top *HostTop, NoTruncate bool) string {
+	var buffer bytes.Buffer
+
+	var build func(children map[int][]TopProcess, parent int) []TextNode
+	build = func(children map[int][]TopProcess, parent int) []TextNode {
+		var nodes []TextNode
+		for _, process := range children[parent] {
+			node := TextNode{Line: fmt.Sprintf("%d %s %s", process.PID, process.User, process.command(NoTruncate))}
+			if process.PID != 0 {
+				node.Children = build(children, process.PID)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	var containers []TextNode
+	for _, container := range sortedTopContainers(top) {
+		containerID := container.Id
+		if !NoTruncate {
+			containerID = truncate(containerID, 12)
+		}
+		line := fmt.Sprintf("%s %s %s", container.Name, containerID, container.Image)
+		containers = append(containers, TextNode{Line: line, Children: build(processChildren(container.processes()), 0)})
+	}
+
+	host := top.Host
+	if host == "" {
+		host = "(host)"
+	}
+	writeTextTree(&buffer, []TextNode{{Line: host, Children: containers}}, "")
+
+	return buffer.String()
+}
+
+func jsonTopToDot(top *HostTop, NoTruncate bool) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph top {\n")
+	buffer.WriteString(" rankdir=LR;\n")
+
+	host := top.Host
+	if host == "" {
+		host = "(host)"
+	}
+	buffer.WriteString(fmt.Sprintf(" \"host\" [label=\"%s\",shape=box3d];\n", dotEscape(host)))
+
+	for _, container := range sortedTopContainers(top) {
+		containerID := container.Id
+		if !NoTruncate {
+			containerID = truncate(containerID, 12)
+		}
+		buffer.WriteString(fmt.Sprintf(" \"%s\" [label=\"%s\\n%s\\n%s\",shape=box,fillcolor=\"paleturquoise\",style=\"filled\"];\n", container.Id, dotEscape(container.Image), dotEscape(container.Name), containerID))
+		buffer.WriteString(fmt.Sprintf(" \"host\" -> \"%s\"\n", container.Id))
+
+		children := processChildren(container.processes())
+		var walk func(parentNode string, parent int)
+		walk = func(parentNode string, parent int) {
+			for _, process := range children[parent] {
+				node := fmt.Sprintf("%s:%d", container.Id, process.PID)
+				buffer.WriteString(fmt.Sprintf(" \"%s\" [label=\"%d %s\\n%s\",shape=ellipse];\n", node, process.PID, dotEscape(process.User), dotEscape(process.command(NoTruncate))))
+				buffer.WriteString(fmt.Sprintf(" \"%s\" -> \"%s\"\n", parentNode, node))
+				if process.PID != 0 {
+					walk(node, process.PID)
+				}
+			}
+		}
+		walk(container.Id, 0)
+	}
+
+	buffer.WriteString("}\n")
+
+	return buffer.String()
+}
+
+func init() {
+	parser.AddCommand("top",
+		"Visualize the processes running in docker containers.",
+		"",
+		&topCommand)
+}
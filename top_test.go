@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const webTopJSON = `{"Titles":["UID","PID","PPID","C","STIME","TTY","TIME","CMD"],"Processes":[` +
+	`["root","2301","2280","0","10:01","?","00:00:00","nginx: master process nginx -g daemon off;"],` +
+	`["101","2350","2301","0","10:01","?","00:00:00","nginx: worker process"],` +
+	`["101","2351","2301","0","10:01","?","00:00:00","nginx: worker process"]]}`
+
+const apiTopJSON = `{"Titles":["USER","PID","PPID","COMMAND"],"Processes":[` +
+	`["app","2410","2402","/usr/local/bin/api --port 8080"],` +
+	`["root","2402","2390","/sbin/tini -- /usr/local/bin/api --port 8080"]]}`
+
+var topJSON = `{"Host":"docker1","Containers":[` +
+	`{"Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Name":"web","Image":"nginx:latest",` + webTopJSON[1:] + `,` +
+	`{"Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Name":"api","Image":"shop/api:1.0",` + apiTopJSON[1:] +
+	`]}`
+
+func Test_TopTree(t *testing.T) {
+	top, err := parseTopJSON([]byte(topJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := jsonTopToTree(top, false)
+	expected := "└─docker1\n" +
+		"  ├─api 2d1f5b4e6c3a shop/api:1.0\n" +
+		"  │ └─2402 root /sbin/tini -- /usr/local/bin/api --port 8080\n" +
+		"  │   └─2410 app /usr/local/bin/api --port 8080\n" +
+		"  └─web 1c0e4a3d5b2f nginx:latest\n" +
+		"    └─2301 root nginx: master process nginx -g daemon off;\n" +
+		"      ├─2350 101 nginx: worker process\n" +
+		"      └─2351 101 nginx: worker process\n"
+	if result != expected {
+		t.Fatalf("top tree '%s' did not match '%s'", result, expected)
+	}
+
+	result = jsonTopToDot(top, false)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?m)^ "host" \[label="docker1",shape=box3d\];$`,
+		`(?m)^ "host" -> "1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706"$`,
+		`(?m)^ "1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706:2301" \[label="2301 root\\nnginx: master process nginx -g daemon off;",shape=ellipse\];$`,
+		`(?m)^ "1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706:2301" -> "1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706:2350"$`,
+		`(?m)^ "2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807" -> "2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807:2402"$`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("top dot content '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+	// commands are shown as they are, escaped rather than cleaned up
+	shellTop := HostTop{Host: "docker1", Containers: []ContainerTop{{
+		Id:        "1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706",
+		Name:      "shell",
+		Image:     "busybox:latest",
+		Titles:    []string{"PID", "PPID", "USER", "CMD"},
+		Processes: [][]string{{"1", "0", "root", `/bin/sh -c echo "a" a\`}},
+	}}}
+	result = jsonTopToDot(&shellTop, false)
+	expected = ` [label="1 root\n/bin/sh -c echo \"a\" a\\",shape=ellipse];`
+	if !strings.Contains(result, expected) {
+		t.Fatalf("top dot content '%s' did not contain '%s'", result, expected)
+	}
+}
+
+// Test_TopDaemon lists processes through the client from a stand-in
+// daemon, the same way dockviz does against a real one.
+func Test_TopDaemon(t *testing.T) {
+	responses := map[string]string{
+		"/info":            `{"Name":"docker1"}`,
+		"/containers/json": `[{"Id":"1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706","Names":["/web"],"Image":"nginx:latest","Status":"Up 1 minute"},{"Id":"2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807","Names":["/api"],"Image":"shop/api:1.0","Status":"Up 1 minute"},{"Id":"3e2a6c5f7d4b1c0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a0908","Names":["/gone"],"Image":"busybox:latest","Status":"Up 1 minute"},{"Id":"4f3b7d6a8e5c2d1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a09","Names":["/stopped"],"Image":"busybox:latest","Status":"Up 1 minute"}]`,
+		"/containers/1c0e4a3d5b2f9a8e7d6c5b4a392817161514131211100f0e0d0c0b0a09080706/top": webTopJSON,
+		"/containers/2d1f5b4e6c3a0b9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a090807/top": apiTopJSON,
+	}
+	client := newStandInDaemon(t, responses, map[string]int{
+		// the container stopped between being listed and asked, while
+		// "gone" was removed and has no response at all
+		"/containers/4f3b7d6a8e5c2d1b0a9f8e7d6c5b4a392817161514131211100f0e0d0c0b0a09/top": http.StatusConflict,
+	})
+	top, err := listTop(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := jsonTopToTree(top, false)
+	if !strings.HasPrefix(result, "└─docker1\n  ├─api 2d1f5b4e6c3a shop/api:1.0\n") || strings.Contains(result, "gone") || strings.Contains(result, "stopped") {
+		t.Fatalf("top tree '%s' was not as expected", result)
+	}
+}
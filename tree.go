@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+)
+
+// TextNode is one line of a terminal tree, drawn with the same box drawing
+// prefixes as the images tree.
+type TextNode struct {
+	Line     string
+	Children []TextNode
+}
+
+func writeTextTree(buffer *bytes.Buffer, nodes []TextNode, prefix string) {
+	for index, node := range nodes {
+		if index+1 == len(nodes) {
+			buffer.WriteString(prefix + "└─" + node.Line + "\n")
+			writeTextTree(buffer, node.Children, prefix+"  ")
+		} else {
+			buffer.WriteString(prefix + "├─" + node.Line + "\n")
+			writeTextTree(buffer, node.Children, prefix+"│ ")
+		}
+	}
+}
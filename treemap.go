@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TreemapRect is the area given to an image.  Each image gets one rect for
+// itself and everything built on top of it, plus a Self rect, inside that,
+// for the size of its own layer.
+type TreemapRect struct {
+	Image Image
+	Depth int
+	Total int64
+	Self  bool
+	X     float64
+	Y     float64
+	W     float64
+	H     float64
+}
+
+// 256 colour backgrounds used for the terminal treemap, chosen so that
+// white text stays readable on all of them
+var treemapTermColors = []int{24, 30, 61, 65, 96, 130, 67, 95, 131, 66, 60, 94}
+
+var treemapSVGColors = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+func jsonToTreemap(roots []Image, byParent map[string][]Image, width int, height int) string {
+	var buffer bytes.Buffer
+
+	// terminal cells are about twice as tall as they are wide, so lay out
+	// on a stretched canvas to keep the rects roughly square on screen
+	rects := layoutTreemap(roots, byParent, float64(width), float64(height*2))
+
+	colors := make([][]int, height)
+	text := make([][]rune, height)
+	for row := 0; row < height; row++ {
+		colors[row] = make([]int, width)
+		text[row] = []rune(strings.Repeat(" ", width))
+	}
+
+	colorFor := make(map[string]int)
+	for _, rect := range rects {
+		if !rect.Self {
+			continue
+		}
+		color := treemapTermColors[len(colorFor)%len(treemapTermColors)]
+		colorFor[rect.Image.Id] = color
+		x0, y0, x1, y1 := treemapCells(rect, width, height)
+		for row := y0; row < y1; row++ {
+			for col := x0; col < x1; col++ {
+				colors[row][col] = color
+			}
+		}
+	}
+
+	// label tagged images, deepest last so the most specific tag wins
+	sort.SliceStable(rects, func(a, b int) bool {
+		return rects[a].Depth < rects[b].Depth
+	})
+	for _, rect := range rects {
+		if rect.Self || rect.Image.RepoTags[0] == "<none>:<none>" {
+			continue
+		}
+		x0, y0, x1, y1 := treemapCells(rect, width, height)
+		if x1 <= x0 || y1 <= y0 {
+			continue
+		}
+		label := []rune(fmt.Sprintf("%s %s", rect.Image.RepoTags[0], humanSize(rect.Total)))
+		for i := 0; i < len(label) && x0+i < x1; i++ {
+			text[y0][x0+i] = label[i]
+		}
+	}
+
+	for row := 0; row < height; row++ {
+		current := -1
+		for col := 0; col < width; col++ {
+			if colors[row][col] != current {
+				current = colors[row][col]
+				if current == 0 {
+					buffer.WriteString("\x1b[0m")
+				} else {
+					buffer.WriteString(fmt.Sprintf("\x1b[48;5;%dm\x1b[97m", current))
+				}
+			}
+			buffer.WriteRune(text[row][col])
+		}
+		buffer.WriteString("\x1b[0m\n")
+	}
+
+	// legend of the tagged images, since labels may not fit in their rects
+	for _, rect := range rects {
+		if rect.Self || rect.Image.RepoTags[0] == "<none>:<none>" {
+			continue
+		}
+		if color, ok := colorFor[rect.Image.Id]; ok {
+			buffer.WriteString(fmt.Sprintf("\x1b[48;5;%dm  \x1b[0m", color))
+		} else {
+			buffer.WriteString("  ")
+		}
+		buffer.WriteString(fmt.Sprintf(" %s %s\n", strings.Join(rect.Image.RepoTags, ", "), humanSize(rect.Total)))
+	}
+
+	return buffer.String()
+}
+
+func jsonToTreemapSVG(roots []Image, byParent map[string][]Image, width int, height int) string {
+	var buffer bytes.Buffer
+
+	rects := layoutTreemap(roots, byParent, float64(width), float64(height))
+
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	buffer.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"11\">\n", width, height, width, height))
+
+	selfCount := 0
+	for _, rect := range rects {
+		if rect.Self {
+			buffer.WriteString(fmt.Sprintf(" <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%s\"><title>%s</title></rect>\n", rect.X, rect.Y, rect.W, rect.H, treemapSVGColors[selfCount%len(treemapSVGColors)], html.EscapeString(treemapTitle(rect))))
+			selfCount++
+		} else {
+			buffer.WriteString(fmt.Sprintf(" <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"none\" stroke=\"white\" stroke-width=\"%d\"/>\n", rect.X, rect.Y, rect.W, rect.H, treemapStroke(rect.Depth)))
+		}
+	}
+
+	for _, rect := range rects {
+		if !rect.Self && rect.Image.RepoTags[0] != "<none>:<none>" && rect.W > 40 && rect.H > 14 {
+			buffer.WriteString(fmt.Sprintf(" <text x=\"%.1f\" y=\"%.1f\" fill=\"white\">%s</text>\n", rect.X+3, rect.Y+12, html.EscapeString(fmt.Sprintf("%s %s", rect.Image.RepoTags[0], humanSize(rect.Total)))))
+		}
+	}
+
+	buffer.WriteString("</svg>\n")
+
+	return buffer.String()
+}
+
+func treemapTitle(rect TreemapRect) string {
+	title := fmt.Sprintf("%s Size: %s", truncate(stripPrefix(rect.Image.OrigId), 12), humanSize(rect.Image.Size))
+	if rect.Image.RepoTags[0] != "<none>:<none>" {
+		title = title + " Tags: " + strings.Join(rect.Image.RepoTags, ", ")
+	}
+	if rect.Image.CreatedBy != "" {
+		title = title + " (" + SanitizeCommand(rect.Image.CreatedBy, 100) + ")"
+	}
+	return title
+}
+
+func treemapStroke(depth int) int {
+	if depth < 3 {
+		return 3 - depth
+	}
+	return 1
+}
+
+func treemapCells(rect TreemapRect, width int, height int) (int, int, int, int) {
+	x0 := int(math.Round(rect.X))
+	x1 := int(math.Round(rect.X + rect.W))
+	y0 := int(math.Round(rect.Y / 2))
+	y1 := int(math.Round((rect.Y + rect.H) / 2))
+	if x1 > width {
+		x1 = width
+	}
+	if y1 > height {
+		y1 = height
+	}
+	if y0 >= height {
+		y0 = height - 1
+	}
+	return x0, y0, x1, y1
+}
+
+// layoutTreemap nests every image inside its parent, sizing each one by
+// the layer sizes of itself and all of its descendants.
+func layoutTreemap(roots []Image, byParent map[string][]Image, width float64, height float64) []TreemapRect {
+	var rects []TreemapRect
+
+	totals := make(map[string]int64)
+	var total func(Image) int64
+	total = func(image Image) int64 {
+		sum := image.Size
+		for _, child := range byParent[image.Id] {
+			sum = sum + total(child)
+		}
+		totals[image.Id] = sum
+		return sum
+	}
+	for _, root := range roots {
+		total(root)
+	}
+
+	var place func(images []Image, self *Image, depth int, x, y, w, h float64)
+	place = func(images []Image, self *Image, depth int, x, y, w, h float64) {
+		var weights []float64
+		var items []*Image
+		for i := range images {
+			if totals[images[i].Id] > 0 {
+				weights = append(weights, float64(totals[images[i].Id]))
+				items = append(items, &images[i])
+			}
+		}
+		if self != nil && self.Size > 0 {
+			weights = append(weights, float64(self.Size))
+			items = append(items, nil)
+		}
+
+		for i, box := range squarify(weights, x, y, w, h) {
+			if items[i] == nil {
+				rects = append(rects, TreemapRect{*self, depth - 1, self.Size, true, box[0], box[1], box[2], box[3]})
+				continue
+			}
+			image := *items[i]
+			rects = append(rects, TreemapRect{image, depth, totals[image.Id], false, box[0], box[1], box[2], box[3]})
+			place(byParent[image.Id], &image, depth+1, box[0], box[1], box[2], box[3])
+		}
+	}
+	place(roots, nil, 0, 0, 0, width, height)
+
+	return rects
+}
+
+// squarify splits the rect into one box (x, y, w, h) per weight, in the same
+// order as the weights, using the squarified treemap algorithm of Bruls,
+// Huizing and van Wijk.
+func squarify(weights []float64, x float64, y float64, w float64, h float64) [][4]float64 {
+	boxes := make([][4]float64, len(weights))
+
+	sum := 0.0
+	for _, weight := range weights {
+		sum = sum + weight
+	}
+	if sum <= 0 || w <= 0 || h <= 0 {
+		return boxes
+	}
+
+	// work largest first, scaling weights to areas
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]] > weights[order[b]]
+	})
+	areas := make([]float64, len(weights))
+	for i, weight := range weights {
+		areas[i] = weight * w * h / sum
+	}
+
+	worst := func(row []int, side float64) float64 {
+		rowSum, rowMin, rowMax := 0.0, math.Inf(1), 0.0
+		for _, i := range row {
+			rowSum = rowSum + areas[i]
+			rowMin = math.Min(rowMin, areas[i])
+			rowMax = math.Max(rowMax, areas[i])
+		}
+		return math.Max(side*side*rowMax/(rowSum*rowSum), rowSum*rowSum/(side*side*rowMin))
+	}
+
+	var row []int
+	for len(order) > 0 {
+		side := math.Min(w, h)
+		next := order[0]
+		if len(row) == 0 || worst(append(row[:len(row):len(row)], next), side) <= worst(row, side) {
+			row = append(row, next)
+			order = order[1:]
+			if len(order) > 0 {
+				continue
+			}
+		}
+
+		// lay the row out along the shorter side and carry on in the rest
+		rowSum := 0.0
+		for _, i := range row {
+			rowSum = rowSum + areas[i]
+		}
+		offset := 0.0
+		if w >= h {
+			rowWidth := rowSum / h
+			for _, i := range row {
+				boxes[i] = [4]float64{x, y + offset, rowWidth, areas[i] / rowWidth}
+				offset = offset + areas[i]/rowWidth
+			}
+			x, w = x+rowWidth, w-rowWidth
+		} else {
+			rowHeight := rowSum / w
+			for _, i := range row {
+				boxes[i] = [4]float64{x + offset, y, areas[i] / rowHeight, rowHeight}
+				offset = offset + areas[i]/rowHeight
+			}
+			y, h = y+rowHeight, h-rowHeight
+		}
+		row = nil
+	}
+
+	return boxes
+}
+
+// terminalSize uses the COLUMNS and LINES environment variables when they
+// are exported, falling back to a standard 80x24 terminal.
+func terminalSize() (int, int) {
+	width, height := 80, 24
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		width = columns
+	}
+	if lines, err := strconv.Atoi(os.Getenv("LINES")); err == nil && lines > 1 {
+		height = lines - 1
+	}
+	return width, height
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func Test_Squarify(t *testing.T) {
+	weights := []float64{6, 6, 4, 3, 2, 2, 1}
+	boxes := squarify(weights, 0, 0, 6, 4)
+
+	for i, box := range boxes {
+		area := box[2] * box[3]
+		if math.Abs(area-weights[i]) > 1e-9 {
+			t.Fatalf("box %d has area %f, expected %f", i, area, weights[i])
+		}
+		if box[0] < -1e-9 || box[1] < -1e-9 || box[0]+box[2] > 6+1e-9 || box[1]+box[3] > 4+1e-9 {
+			t.Fatalf("box %d %+v is outside the rect", i, box)
+		}
+	}
+
+	// as in the paper, the two largest share the first column as 3x2 boxes
+	for _, box := range boxes[:2] {
+		if math.Abs(box[2]-3) > 1e-9 || math.Abs(box[3]-2) > 1e-9 {
+			t.Fatalf("largest boxes were not laid out together: %+v", boxes[:2])
+		}
+	}
+}
+
+func Test_Treemap(t *testing.T) {
+	treemapJSON := `[{"VirtualSize":700,"Size":300,"RepoTags":["app:latest"],"ParentId":"base","Id":"app","Created":1386142123},{"VirtualSize":500,"Size":100,"RepoTags":["<none>:<none>"],"ParentId":"base","Id":"tool","Created":1386142123},{"VirtualSize":400,"Size":400,"RepoTags":["base:latest"],"ParentId":"","Id":"base","Created":1386114144}]`
+
+	im, _ := parseImagesJSON([]byte(treemapJSON))
+	for i := range *im {
+		(*im)[i].OrigId = (*im)[i].Id
+	}
+	roots := collectRoots(im)
+	byParent := collectChildren(im)
+
+	rects := layoutTreemap(roots, byParent, 80, 40)
+	areas := make(map[string]float64)
+	for _, rect := range rects {
+		if rect.Self {
+			areas[rect.Image.Id] = rect.W * rect.H
+		} else if rect.Image.Id == "base" && math.Abs(rect.W*rect.H-3200) > 1e-6 {
+			t.Fatalf("root rect %+v does not fill the treemap", rect)
+		}
+	}
+	if math.Abs(areas["base"]-1600) > 1e-6 || math.Abs(areas["app"]-1200) > 1e-6 || math.Abs(areas["tool"]-400) > 1e-6 {
+		t.Fatalf("layer areas %+v are not proportional to layer sizes", areas)
+	}
+
+	result := jsonToTreemap(roots, byParent, 80, 20)
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	if len(lines) != 22 {
+		t.Fatalf("terminal treemap '%s' should have 20 rows and 2 legend lines", result)
+	}
+	for _, regexp := range compileRegexps(t, []string{
+		`base:latest 800\.0 B`,
+		`app:latest 300\.0 B`,
+		`\x1b\[48;5;\d+m`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("terminal treemap '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+
+	result = jsonToTreemapSVG(roots, byParent, 400, 300)
+	for _, regexp := range compileRegexps(t, []string{
+		`(?s)^<\?xml.*<svg .*</svg>\n$`,
+		`<title>app Size: 300\.0 B Tags: app:latest</title>`,
+		`<text [^>]*>base:latest 800\.0 B</text>`,
+	}) {
+		if !regexp.MatchString(result) {
+			t.Fatalf("svg treemap '%s' did not match regexp '%s'", result, regexp)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"errors"
+	"net/http"
 	"os"
 	"path"
+	"sync"
 
 	"github.com/fsouza/go-dockerclient"
 )
@@ -63,3 +65,46 @@ func getAPIVersion(client *docker.Client) ([]int, error) {
 
 	return ver, nil
 }
+
+// how many containers the daemon is asked about at once, since asking about
+// each one in turn is slow on a busy host
+const daemonConcurrency = 8
+
+// forEachConcurrently calls fn for each index up to n, daemonConcurrency at
+// a time, and returns the first error any call gave.
+func forEachConcurrently(n int, fn func(i int) error) error {
+	var wait sync.WaitGroup
+	var lock sync.Mutex
+	var firstErr error
+
+	slots := make(chan bool, daemonConcurrency)
+	for i := 0; i < n; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			slots <- true
+			defer func() { <-slots }()
+
+			if err := fn(i); err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	return firstErr
+}
+
+// stoppedContainer checks for a container that was removed, or that is
+// still there but no longer running, since it was listed.
+func stoppedContainer(err error) bool {
+	if _, removed := err.(*docker.NoSuchContainer); removed {
+		return true
+	}
+	apiErr, ok := err.(*docker.Error)
+	return ok && apiErr.Status == http.StatusConflict
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/fsouza/go-dockerclient"
+
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newStandInDaemon serves recorded responses by URL path, so tests go
+// through the client the same way dockviz does against a real daemon.
+// Paths in statuses fail with that status code, and any other path is a
+// 404, which the client reads as the container having been removed.
+func newStandInDaemon(t *testing.T, responses map[string]string, statuses map[string]int) *docker.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, ok := statuses[r.URL.Path]; ok {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, "No such container", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := docker.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}